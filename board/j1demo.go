@@ -0,0 +1,50 @@
+// Package board wires peripherals of J1 boards onto the bus of a core
+package board
+
+import (
+	"github.com/dim13/j1"
+	"github.com/dim13/j1/device"
+)
+
+// Unmapped is value j1demo reads from ports nothing decodes
+const Unmapped = 0x0946
+
+// J1Demo is board of docs/j1demo/verilog/top.v
+type J1Demo struct {
+	Clock      *device.Clock
+	Multiplier *device.Multiplier
+	Switches   *device.Switches
+	Serial     *device.Serial
+	core       *j1.Core
+	sent       uint64 // cycles at last byte on serial
+}
+
+// NewJ1Demo attaches peripherals of j1demo to core: clock at 0x6000,
+// multiplier at 0x6100, push buttons at 0x4500 and serial transmit line at
+// 0x5000, which goes to con. Other ports read Unmapped, so devices attached
+// later are not seen. Console ports of core are left as they are, the
+// firmware does not use them.
+func NewJ1Demo(c *j1.Core, con j1.Console) *J1Demo {
+	b := &J1Demo{
+		Clock:      device.NewClock(c.Cycles),
+		Multiplier: new(device.Multiplier),
+		Switches:   new(device.Switches),
+		core:       c,
+	}
+	b.Serial = device.NewSerial(func(v uint16) {
+		b.sent = c.Cycles()
+		con.Write(v)
+	})
+	c.Attach(0x6000, device.ClockSize, b.Clock)
+	c.Attach(0x6100, device.MultiplierSize, b.Multiplier)
+	c.Attach(0x4500, device.SwitchesSize, b.Switches)
+	c.Attach(0x5000, 1, b.Serial)
+	c.Attach(0x4000, 0xc000, device.Floating(Unmapped))
+	return b
+}
+
+// Idle is number of cycles since last byte on serial, or since start if
+// none was sent. The firmware goes silent once it reaches VGA setup.
+func (b *J1Demo) Idle() uint64 {
+	return b.core.Cycles() - b.sent
+}
@@ -0,0 +1,57 @@
+package board
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dim13/j1"
+	"github.com/dim13/j1/loader"
+)
+
+// serial console, cancels once want is written
+type serial struct {
+	out    bytes.Buffer
+	want   string
+	cancel func()
+}
+
+func (s *serial) Read() uint16 { return 0 }
+func (s *serial) Len() uint16  { return 0 }
+func (s *serial) Stop()        { s.cancel() }
+
+func (s *serial) Write(v uint16) {
+	s.out.WriteByte(byte(v))
+	if strings.Contains(s.out.String(), s.want) {
+		s.cancel()
+	}
+}
+
+func TestJ1Demo(t *testing.T) {
+	image, err := loader.Load("../testdata/j1.mem", loader.Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	con := &serial{want: "main.fs LINE 719", cancel: cancel} // snap after banner
+	vm := j1.New(con)
+	if _, err := vm.Write(image); err != nil {
+		t.Fatal(err)
+	}
+	b := NewJ1Demo(vm, con)
+	if err := vm.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if out := con.out.String(); !strings.Contains(out, "Welcome! Built ") || !strings.Contains(out, con.want) {
+		t.Fatalf("got %q after %v cycles", con.out.String(), vm.Cycles())
+	}
+	if n := b.Idle(); n > 4096 {
+		t.Errorf("idle for %v cycles after last byte", n)
+	}
+	if us := vm.Cycles() / 33; us < 32*30000 {
+		t.Errorf("booted after %vµs, frob sleeps 960ms", us)
+	}
+}
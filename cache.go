@@ -0,0 +1,213 @@
+package j1
+
+// kinds of predecoded instructions
+const (
+	kindNone uint8 = iota // not decoded yet
+	kindLit
+	kindJump
+	kindCond
+	kindCall
+	kindFault // fetch not permitted
+	kindALU
+)
+
+// flags of decoded ALU instruction
+const (
+	fRtoPC uint8 = 1 << iota
+	fTtoN
+	fTtoR
+	fNtoAtT
+	fNtoIoAtT
+	fMem  // reads or writes memory at T
+	fSlow // run by alu: i/o, invalid opcode or guarded memory
+)
+
+// decoded instruction, kept per memory cell until the cell is written. It
+// is packed into 8 bytes.
+type decoded struct {
+	kind       uint8
+	op         Op
+	flags      uint8
+	ddir, rdir int8
+	arg        uint16 // literal value or target cell
+}
+
+// alu instruction of decoded entry
+func (e *decoded) alu() ALU {
+	return ALU{
+		Opcode:   e.op,
+		RtoPC:    e.flags&fRtoPC != 0,
+		TtoN:     e.flags&fTtoN != 0,
+		TtoR:     e.flags&fTtoR != 0,
+		NtoAtT:   e.flags&fNtoAtT != 0,
+		NtoIoAtT: e.flags&fNtoIoAtT != 0,
+		Ddir:     e.ddir,
+		Rdir:     e.rdir,
+	}
+}
+
+// flag value of b
+func flag(b bool, f uint8) uint8 {
+	if b {
+		return f
+	}
+	return 0
+}
+
+// checkEvery is number of instructions Run executes between checks for
+// cancellation, i/o access checks at once
+const checkEvery = 1 << 12
+
+// decode cell at pc into cache
+func (c *Core) decode(pc uint16) *decoded {
+	d := &c.code[pc]
+	if !c.executable(pc) {
+		d.kind = kindFault
+		return d
+	}
+	switch v := c.isa.Decode(c.memory[pc]).(type) {
+	case Literal:
+		d.kind, d.arg = kindLit, v.value()
+	case Jump:
+		d.kind, d.arg = kindJump, v.value()
+	case Conditional:
+		d.kind, d.arg = kindCond, v.value()
+	case Call:
+		d.kind, d.arg = kindCall, v.value()
+	case ALU:
+		d.kind, d.op, d.ddir, d.rdir = kindALU, v.Opcode, v.Ddir, v.Rdir
+		d.flags = flag(v.RtoPC, fRtoPC) | flag(v.TtoN, fTtoN) | flag(v.TtoR, fTtoR) |
+			flag(v.NtoAtT, fNtoAtT) | flag(v.NtoIoAtT, fNtoIoAtT) |
+			flag(v.NtoAtT || v.Opcode == OpAtT, fMem) |
+			flag(v.NtoIoAtT || v.Opcode == OpIoAtT || v.Opcode >= nOps ||
+				c.guarded() && (v.NtoAtT || v.Opcode == OpAtT), fSlow)
+	}
+	return d
+}
+
+// flush drops all decoded instructions
+func (c *Core) flush() {
+	c.code = [len(c.memory)]decoded{}
+}
+
+// written cell is decoded again, and copied to peers
+func (c *Core) written(cell uint16) {
+	c.invalidate(cell)
+	for _, p := range c.peers {
+		p.memory[cell] = c.memory[cell]
+		p.invalidate(cell)
+	}
+}
+
+func (c *Core) invalidate(cell uint16) {
+	c.code[cell].kind = kindNone
+}
+
+// step executes instruction at pc, as Execute(Fetch()) does
+func (c *Core) step() {
+	c.run(1)
+}
+
+// run executes up to n instructions from cache, as Execute(Fetch()) does,
+// and returns early after i/o access. Registers are kept in locals and
+// stored back before i/o, which may look at them.
+func (c *Core) run(n int) {
+	pc, st0, cycles := c.pc, c.st0, c.cycles
+	d, r := &c.d, &c.r
+	dsp, rsp := d.sp, r.sp
+	c.io = false
+	for ; n > 0; n-- {
+		e := &c.code[pc]
+		cycles++
+		pc = (pc + 1) & pcMask
+		if e.kind < kindALU {
+			switch e.kind {
+			case kindNone:
+				// decode and run again
+				pc, cycles, n = (pc-1)&pcMask, cycles-1, n+1
+				c.decode(pc)
+			case kindLit:
+				dsp = (dsp + 1) & 0x1f
+				d.data[dsp] = st0
+				st0 = e.arg
+			case kindJump:
+				pc = e.arg
+			case kindCall:
+				rsp = (rsp + 1) & 0x1f
+				r.data[rsp] = pc << 1
+				pc = e.arg
+			case kindCond:
+				if st0 == 0 {
+					pc = e.arg
+				}
+				st0 = d.data[dsp]
+				dsp = (dsp - 1) & 0x1f
+			case kindFault:
+				c.pc, c.st0, c.cycles, d.sp, r.sp = pc, st0, cycles, dsp, rsp
+				c.fail(ErrNotExecutable)
+				return
+			}
+			continue
+		}
+		T, N, R := st0, d.data[dsp], r.data[rsp]
+		if e.flags&(fSlow|fMem) != 0 && (e.flags&fSlow != 0 || T&ioMask != 0) {
+			c.pc, c.st0, c.cycles, d.sp, r.sp = pc, st0, cycles, dsp, rsp
+			a := e.alu()
+			c.alu(&a)
+			pc, st0, dsp, rsp = c.pc, c.st0, d.sp, r.sp
+			if c.io {
+				return
+			}
+			continue
+		}
+		if e.flags&fRtoPC != 0 {
+			pc = R >> 1 & pcMask
+		}
+		if e.flags&fNtoAtT != 0 {
+			c.memory[T>>1] = N
+			c.written(T >> 1)
+		}
+		switch e.op {
+		case OpT:
+		case OpN:
+			st0 = N
+		case OpTplusN:
+			st0 = T + N
+		case OpTandN:
+			st0 = T & N
+		case OpTorN:
+			st0 = T | N
+		case OpTxorN:
+			st0 = T ^ N
+		case OpNotT:
+			st0 = ^T
+		case OpNeqT:
+			st0 = boolValue(N == T)
+		case OpNleT:
+			st0 = boolValue(int16(N) < int16(T))
+		case OpNrshiftT:
+			st0 = N >> (T & 0xf)
+		case OpTminus1:
+			st0 = T - 1
+		case OpR:
+			st0 = R
+		case OpAtT:
+			st0 = c.memory[T>>1]
+		case OpNlshiftT:
+			st0 = N << (T & 0xf)
+		case OpDepth:
+			st0 = uint16(rsp)<<8 | uint16(dsp)
+		case OpNuleT:
+			st0 = boolValue(N < T)
+		}
+		dsp = (dsp + uint8(e.ddir)) & 0x1f
+		rsp = (rsp + uint8(e.rdir)) & 0x1f
+		if e.flags&fTtoN != 0 {
+			d.data[dsp] = T
+		}
+		if e.flags&fTtoR != 0 {
+			r.data[rsp] = T
+		}
+	}
+	c.pc, c.st0, c.cycles, d.sp, r.sp = pc, st0, cycles, dsp, rsp
+}
@@ -0,0 +1,110 @@
+package j1
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// run eForth image with boot script until bye
+func run(tb testing.TB, image []byte, script string) *Core {
+	tb.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	c := New(&stopConsole{cancel: cancel})
+	if _, err := c.Write(image); err != nil {
+		tb.Fatal(err)
+	}
+	if err := c.Boot([]byte(script)); err != nil {
+		tb.Fatal(err)
+	}
+	c.Run(ctx)
+	if ctx.Err() == context.DeadlineExceeded {
+		tb.Fatal("bye not reached")
+	}
+	return c
+}
+
+func TestInvalidate(t *testing.T) {
+	c := New(&mocConsole{})
+	for i, ins := range []Instruction{
+		Literal(Encode(ALU{Opcode: OpNotT})),
+		Literal(8),
+		ALU{Opcode: OpN, NtoAtT: true, Ddir: -1}, // !
+		ALU{Opcode: OpN, Ddir: -1},
+		Literal(1), // overwritten
+		Jump(5),
+	} {
+		c.memory[i] = Encode(ins)
+	}
+	c.pc = 4
+	c.step()
+	if c.st0 != 1 {
+		t.Fatalf("got %v, want 1", c.st0)
+	}
+	c.Reset()
+	c.run(5)
+	if c.st0 != 0xffff {
+		t.Errorf("got %0.4X, want FFFF", c.st0)
+	}
+}
+
+func TestRun(t *testing.T) {
+	image := eforthImage(t)
+	exec, cache := New(&mocConsole{}), New(&mocConsole{})
+	for _, c := range []*Core{exec, cache} {
+		if _, err := c.Write(image); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Boot([]byte(": sq dup * ;\n7 sq 3000 !")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for exec.Cycles() < 20000 {
+		exec.Execute(exec.Fetch())
+	}
+	for cache.Cycles() < 20000 {
+		cache.run(int(20000 - cache.Cycles()))
+	}
+	cmp(t, *cache, *exec)
+	if cache.memory != exec.memory {
+		t.Error("memory differs")
+	}
+}
+
+func eforthImage(tb testing.TB) []byte {
+	image, err := os.ReadFile("testdata/j1e.bin")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return image
+}
+
+func benchmark(b *testing.B, script string) {
+	image := eforthImage(b)
+	var cycles uint64
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cycles += run(b, image, script).Cycles()
+	}
+	b.ReportMetric(float64(cycles)/b.Elapsed().Seconds()/1e6, "MIPS")
+}
+
+func BenchmarkBoot(b *testing.B) {
+	benchmark(b, "bye")
+}
+
+func fibonacci(tb testing.TB) string {
+	samples, err := os.ReadFile("docs/samples.fs")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	// boot script is evaluated as one line
+	return strings.ReplaceAll(string(samples), "\n", " ") + " decimal 20 fibonacci hex 3000 ! bye"
+}
+
+func BenchmarkFibonacci(b *testing.B) {
+	benchmark(b, fibonacci(b))
+}
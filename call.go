@@ -0,0 +1,64 @@
+package j1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dim13/j1/eforth"
+)
+
+// Call eForth word by name, see CallAt
+func (c *Core) Call(ctx context.Context, name string, args ...uint16) ([]uint16, error) {
+	ca, ok := eforth.Lookup(c.memory[:], name)
+	if !ok {
+		return nil, fmt.Errorf("%v: word not found", name)
+	}
+	return c.CallAt(ctx, ca, args...)
+}
+
+// CallAt calls code at byte address addr the way j1.c executes its
+// entrypoint. Arguments are pushed on the data stack, last one on top.
+// It runs until the matching return and gives back what the word left on
+// the data stack, top last. Data stack is restored afterwards. If ctx is
+// done first, as with a word waiting for input, core is put back as it was
+// before the call and ctx error is returned.
+func (c *Core) CallAt(ctx context.Context, addr uint16, args ...uint16) ([]uint16, error) {
+	base, st0 := c.d.sp, c.st0
+	for _, v := range args {
+		c.d.push(c.st0)
+		c.st0 = v
+	}
+	ret, rsp := c.pc, c.r.sp
+	c.r.push(ret << 1)
+	c.pc = (addr >> 1) & pcMask
+	done := ctx.Done()
+	for n := 0; c.fault == nil && (c.pc != ret || c.r.sp != rsp); n++ {
+		if n%checkEvery == 0 {
+			select {
+			case <-done:
+				c.pc, c.r.sp, c.d.sp, c.st0 = ret, rsp, base, st0
+				return nil, fmt.Errorf("%0.4X: %w", addr, ctx.Err())
+			default:
+			}
+		}
+		c.step()
+	}
+	if c.fault != nil {
+		return nil, c.fault
+	}
+	n := (c.d.sp - base) & 0x1f
+	if n > 0x10 {
+		c.d.sp, c.st0 = base, st0
+		return nil, fmt.Errorf("%0.4X: data stack underflow by %v", addr, 0x20-n)
+	}
+	res := make([]uint16, n)
+	if n > 0 {
+		res[n-1] = c.st0
+		for i := uint8(1); i < n; i++ {
+			res[n-1-i] = c.d.data[(c.d.sp-i+1)&0x1f]
+		}
+		c.st0 = c.d.data[(base+1)&0x1f]
+		c.d.sp = base
+	}
+	return res, nil
+}
@@ -0,0 +1,94 @@
+package j1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCall(t *testing.T) {
+	image, err := os.ReadFile("testdata/j1e.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j1 := New(&mocConsole{})
+	if _, err := j1.Write(image); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name string
+		args []uint16
+		want []uint16
+		err  bool
+	}{
+		{name: "+", args: []uint16{2, 3}, want: []uint16{5}},
+		{name: "dup", args: []uint16{7}, want: []uint16{7, 7}},
+		{name: "swap", args: []uint16{1, 2}, want: []uint16{2, 1}},
+		{name: "um/mod", args: []uint16{100, 0, 7}, want: []uint16{2, 14}},
+		{name: "*", args: []uint16{6, 7}, want: []uint16{42}},
+		{name: "drop", args: []uint16{1, 2}, want: []uint16{1}},
+		{name: "2drop", args: []uint16{1}, err: true}, // drops more than given
+		{name: "nosuch", err: true},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.name, tc.args), func(t *testing.T) {
+			pc, sp, st0 := j1.pc, j1.d.sp, j1.st0
+			got, err := j1.Call(context.Background(), tc.name, tc.args...)
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v", err)
+			}
+			if !tc.err && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+			if j1.pc != pc || j1.d.sp != sp || j1.st0 != st0 {
+				t.Errorf("state not restored: %v", j1)
+			}
+		})
+	}
+}
+
+func TestCallDefined(t *testing.T) {
+	image, err := os.ReadFile("testdata/j1e.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	j1 := New(&stopConsole{cancel: cancel})
+	if _, err := j1.Write(image); err != nil {
+		t.Fatal(err)
+	}
+	j1.Boot([]byte(": fib ( n -- n ) dup 2 < if exit then dup 1- recurse swap 2 - recurse + ; bye"))
+	j1.Run(ctx)
+	got, err := j1.Call(context.Background(), "fib", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint16{55}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCallCancel(t *testing.T) {
+	image, err := os.ReadFile("testdata/j1e.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j1 := New(&mocConsole{})
+	if _, err := j1.Write(image); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	pc, sp, rsp, st0 := j1.pc, j1.d.sp, j1.r.sp, j1.st0
+	if _, err := j1.Call(ctx, "key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if j1.pc != pc || j1.d.sp != sp || j1.r.sp != rsp || j1.st0 != st0 {
+		t.Errorf("state not restored: %v", j1)
+	}
+}
@@ -0,0 +1,357 @@
+// Package cfg builds control flow and call graphs of J1 memory images
+package cfg
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dim13/j1"
+	"github.com/dim13/j1/eforth"
+)
+
+// Block of straight code, addresses in bytes
+type Block struct {
+	Addr  uint16   `json:"addr"`
+	End   uint16   `json:"end"`             // first byte after block
+	Succ  []uint16 `json:"succ,omitempty"`  // following blocks of same word
+	Calls []uint16 `json:"calls,omitempty"` // words called or jumped to
+}
+
+// Word is dictionary entry or other code entered by a call, with its
+// control flow graph
+type Word struct {
+	Name      string   `json:"name"`
+	Addr      uint16   `json:"addr"`
+	Blocks    []Block  `json:"blocks,omitempty"`
+	Calls     []uint16 `json:"calls,omitempty"` // words called or jumped to
+	Refs      []uint16 `json:"refs,omitempty"`  // words compiled as literal
+	Reachable bool     `json:"reachable"`
+	Effect    *Effect  `json:"effect,omitempty"`
+}
+
+// Graph of image, words sorted by address
+type Graph struct {
+	Words []*Word `json:"words"`
+
+	mem     []uint16
+	isa     j1.ISA
+	byAddr  map[uint16]*Word
+	runtime map[uint16]bool // eForth runtime words with inline arguments
+}
+
+// Word by name, most recent definition in case of doubles
+func (g *Graph) Word(name string) (*Word, bool) {
+	for i := len(g.Words) - 1; i >= 0; i-- {
+		if g.Words[i].Name == name {
+			return g.Words[i], true
+		}
+	}
+	return nil, false
+}
+
+// Unreachable words, not called nor referenced from reset vector and roots
+func (g *Graph) Unreachable() []*Word {
+	var ws []*Word
+	for _, w := range g.Words {
+		if !w.Reachable {
+			ws = append(ws, w)
+		}
+	}
+	return ws
+}
+
+// arg is inline argument following call of eForth runtime word
+type arg int
+
+const (
+	argNone   arg = iota
+	argCell       // one cell of data
+	argBranch     // one cell, byte address continued at
+	argJump       // jump instruction, reached by leave
+	argString     // counted string
+	argData       // data or code reached otherwise, call does not return
+)
+
+// runtime words of docs/j1eforth/j1.4th which return elsewhere
+var runtime = map[string]arg{
+	"compile":   argCell,
+	"(to)":      argCell,
+	"(+to)":     argCell,
+	"(next)":    argBranch,
+	"(loop)":    argBranch,
+	"(+loop)":   argBranch,
+	"(do)":      argJump,
+	"(?do)":     argJump,
+	`."|`:       argString,
+	`$"|`:       argString,
+	`<?abort">`: argString,
+	"dovar":     argData,
+	"douser":    argData,
+	"(does>)":   argData,
+}
+
+// analysis state, addresses in cells
+type analysis struct {
+	mem    []uint16
+	isa    j1.ISA
+	dict   *eforth.Dictionary
+	seen   map[uint16]bool
+	leader map[uint16]bool
+	starts map[uint16]bool // words
+	succ   map[uint16][]uint16
+	calls  map[uint16]uint16
+	lits   map[uint16]uint16
+}
+
+// Analyze memory cells from reset vector at address 0, words of eForth
+// dictionary if there is one, and roots given as byte addresses. Words
+// referenced from data only, such as execution vectors, are reachable
+// only when given as roots.
+func Analyze(mem []uint16, isa j1.ISA, roots ...uint16) *Graph {
+	a := &analysis{
+		mem:    mem,
+		isa:    isa,
+		seen:   make(map[uint16]bool),
+		leader: make(map[uint16]bool),
+		starts: make(map[uint16]bool),
+		succ:   make(map[uint16][]uint16),
+		calls:  make(map[uint16]uint16),
+		lits:   make(map[uint16]uint16),
+	}
+	if dict, err := eforth.NewDictionary(mem); err == nil {
+		a.dict = dict
+	}
+	entries := []uint16{0}
+	if len(mem) > 0 {
+		if v, ok := isa.Decode(mem[0]).(j1.Jump); ok {
+			entries = append(entries, uint16(v)) // word run on reset
+		}
+	}
+	for _, r := range roots {
+		entries = append(entries, r>>1)
+	}
+	if a.dict != nil {
+		for _, w := range a.dict.Words() {
+			entries = append(entries, w.Addr>>1)
+		}
+	}
+	for _, pc := range entries {
+		a.starts[pc] = true
+	}
+	a.explore(entries)
+	g := a.graph()
+	g.reach(append([]uint16{0}, roots...))
+	g.infer()
+	return g
+}
+
+func (a *analysis) inside(pc uint16) bool { return int(pc) < len(a.mem) }
+
+// explore code reached from entries
+func (a *analysis) explore(entries []uint16) {
+	work := append([]uint16(nil), entries...)
+	for _, pc := range entries {
+		a.leader[pc] = true
+	}
+	for len(work) > 0 {
+		pc := work[len(work)-1]
+		work = work[:len(work)-1]
+		if !a.inside(pc) || a.seen[pc] {
+			continue
+		}
+		a.seen[pc] = true
+		next, end := a.step(pc)
+		a.succ[pc] = next
+		for _, n := range next {
+			if end {
+				a.leader[n] = true
+			}
+			work = append(work, n)
+		}
+		if t, ok := a.calls[pc]; ok {
+			a.leader[t] = true
+			a.starts[t] = true
+			work = append(work, t)
+		}
+	}
+}
+
+// step gives cells following pc and if block ends at it
+func (a *analysis) step(pc uint16) ([]uint16, bool) {
+	switch v := a.isa.Decode(a.mem[pc]).(type) {
+	case j1.Literal:
+		a.lits[pc] = uint16(v)
+	case j1.Jump:
+		return []uint16{uint16(v)}, true
+	case j1.Conditional:
+		return []uint16{uint16(v), pc + 1}, true
+	case j1.Call:
+		a.calls[pc] = uint16(v)
+		switch a.arg(uint16(v)) {
+		case argCell:
+			return []uint16{pc + 2}, true
+		case argBranch:
+			if !a.inside(pc + 1) {
+				return nil, true
+			}
+			return []uint16{a.mem[pc+1] >> 1, pc + 2}, true
+		case argJump:
+			return []uint16{pc + 1, pc + 2}, true
+		case argString:
+			return []uint16{pc + 1 + a.strlen(pc+1)}, true
+		case argData:
+			return nil, true
+		}
+		return []uint16{pc + 1}, true
+	case j1.ALU:
+		if v.RtoPC {
+			return nil, true
+		}
+	}
+	return []uint16{pc + 1}, false
+}
+
+// arg following call of t
+func (a *analysis) arg(t uint16) arg {
+	if a.dict == nil {
+		return argNone
+	}
+	w, ok := a.dict.At(t << 1)
+	if !ok {
+		return argNone
+	}
+	return runtime[w.Name]
+}
+
+// strlen is size of counted string at pc in cells
+func (a *analysis) strlen(pc uint16) uint16 {
+	if !a.inside(pc) {
+		return 0
+	}
+	return (a.mem[pc]&0xff + 2) >> 1
+}
+
+// owner is word start at or before pc
+func (a *analysis) owner(starts []uint16, pc uint16) uint16 {
+	k := sort.Search(len(starts), func(k int) bool { return starts[k] > pc })
+	if k == 0 {
+		return pc
+	}
+	return starts[k-1]
+}
+
+func (a *analysis) name(pc uint16) string {
+	if a.dict != nil {
+		if w, ok := a.dict.At(pc << 1); ok {
+			return w.Name
+		}
+	}
+	if pc == 0 {
+		return "reset"
+	}
+	return fmt.Sprintf("%0.4X", pc<<1)
+}
+
+// graph of explored code
+func (a *analysis) graph() *Graph {
+	starts := sorted(a.starts)
+	g := &Graph{
+		mem:     a.mem,
+		isa:     a.isa,
+		byAddr:  make(map[uint16]*Word),
+		runtime: make(map[uint16]bool),
+	}
+	for _, pc := range starts {
+		w := &Word{Name: a.name(pc), Addr: pc << 1}
+		g.Words = append(g.Words, w)
+		g.byAddr[pc] = w
+		if a.arg(pc) != argNone {
+			g.runtime[pc] = true
+		}
+	}
+	calls := make(map[*Word]map[uint16]bool)
+	refs := make(map[*Word]map[uint16]bool)
+	add := func(m map[*Word]map[uint16]bool, w *Word, v uint16) {
+		if m[w] == nil {
+			m[w] = make(map[uint16]bool)
+		}
+		m[w][v] = true
+	}
+	for _, pc := range sorted(a.leader) {
+		if !a.seen[pc] {
+			continue
+		}
+		own := a.owner(starts, pc)
+		w := g.byAddr[own]
+		b := Block{Addr: pc << 1}
+		for {
+			if t, ok := a.calls[pc]; ok {
+				b.Calls = append(b.Calls, t<<1)
+				add(calls, w, t)
+			}
+			if v, ok := a.lits[pc]; ok && a.starts[v>>1] && v&1 == 0 {
+				add(refs, w, v>>1)
+			}
+			next := a.succ[pc]
+			if len(next) != 1 || next[0] != pc+1 || a.leader[pc+1] || !a.seen[pc+1] {
+				break
+			}
+			pc++
+		}
+		b.End = (pc + 1) << 1
+		for _, n := range a.succ[pc] {
+			if a.owner(starts, n) != own {
+				b.Calls = append(b.Calls, n<<1)
+				add(calls, w, a.owner(starts, n))
+				continue
+			}
+			b.Succ = append(b.Succ, n<<1)
+		}
+		w.Blocks = append(w.Blocks, b)
+	}
+	for _, w := range g.Words {
+		for _, v := range sorted(calls[w]) {
+			w.Calls = append(w.Calls, v<<1)
+		}
+		for _, v := range sorted(refs[w]) {
+			w.Refs = append(w.Refs, v<<1)
+		}
+	}
+	return g
+}
+
+// reach marks words reached from roots, given in bytes
+func (g *Graph) reach(roots []uint16) {
+	var work []*Word
+	for _, r := range roots {
+		if w, ok := g.byAddr[r>>1]; ok {
+			work = append(work, w)
+		}
+	}
+	for len(work) > 0 {
+		w := work[len(work)-1]
+		work = work[:len(work)-1]
+		if w.Reachable {
+			continue
+		}
+		w.Reachable = true
+		for _, v := range append(w.Calls, w.Refs...) {
+			if u, ok := g.byAddr[v>>1]; ok {
+				work = append(work, u)
+			}
+		}
+	}
+}
+
+func sorted(m map[uint16]bool) []uint16 {
+	s := make([]uint16, 0, len(m))
+	for v := range m {
+		s = append(s, v)
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	return s
+}
+
+func (w Word) String() string {
+	return fmt.Sprintf("%0.4X %v", w.Addr, w.Name)
+}
@@ -0,0 +1,223 @@
+package cfg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dim13/j1"
+	"github.com/dim13/j1/eforth"
+)
+
+func encode(ins ...j1.Instruction) []uint16 {
+	mem := make([]uint16, len(ins))
+	for i, v := range ins {
+		mem[i] = j1.Encode(v)
+	}
+	return mem
+}
+
+func TestAnalyze(t *testing.T) {
+	var (
+		dup = j1.ALU{Opcode: j1.OpT, TtoN: true, Ddir: 1}
+		ret = j1.ALU{Opcode: j1.OpT, RtoPC: true, Rdir: -1}
+	)
+	mem := encode(
+		j1.Jump(2),        // 0000 reset
+		j1.Literal(0),     // 0002 data
+		j1.Call(6),        // 0004 main
+		j1.Conditional(2), // 0006
+		j1.Jump(8),        // 0008 tail call
+		j1.Literal(0x10),  // 000A unused, refers to g, falls into f
+		j1.Call(8),        // 000C f
+		ret,               // 000E
+		dup,               // 0010 g
+		ret,               // 0012
+	)
+	g := Analyze(mem, j1.J1, 0x0a)
+	want := []*Word{
+		{Name: "reset", Addr: 0x00, Calls: []uint16{0x04}, Reachable: true,
+			Blocks: []Block{{Addr: 0x00, End: 0x02, Calls: []uint16{0x04}}}},
+		{Name: "0004", Addr: 0x04, Calls: []uint16{0x0c, 0x10}, Reachable: true,
+			Blocks: []Block{
+				{Addr: 0x04, End: 0x06, Succ: []uint16{0x06}, Calls: []uint16{0x0c}},
+				{Addr: 0x06, End: 0x08, Succ: []uint16{0x04, 0x08}},
+				{Addr: 0x08, End: 0x0a, Calls: []uint16{0x10}},
+			}},
+		{Name: "000A", Addr: 0x0a, Calls: []uint16{0x0c}, Refs: []uint16{0x10}, Reachable: true,
+			Blocks: []Block{{Addr: 0x0a, End: 0x0c, Calls: []uint16{0x0c}}}},
+		{Name: "000C", Addr: 0x0c, Calls: []uint16{0x10}, Reachable: true,
+			Blocks: []Block{
+				{Addr: 0x0c, End: 0x0e, Succ: []uint16{0x0e}, Calls: []uint16{0x10}},
+				{Addr: 0x0e, End: 0x10},
+			}},
+		{Name: "0010", Addr: 0x10, Reachable: true,
+			Blocks: []Block{{Addr: 0x10, End: 0x14}}},
+	}
+	for _, w := range g.Words {
+		w.Effect = nil // see TestEffect
+	}
+	if !reflect.DeepEqual(g.Words, want) {
+		var b bytes.Buffer
+		g.WriteJSON(&b)
+		t.Errorf("got %v", b.String())
+	}
+	if g := Analyze(mem, j1.J1); len(g.Words) != 4 {
+		t.Errorf("without root: got %v words, want 4", len(g.Words))
+	}
+}
+
+func TestEForth(t *testing.T) {
+	mem := make([]uint16, len(eforth.Image)/2)
+	for i := range mem {
+		mem[i] = binary.LittleEndian.Uint16(eforth.Image[2*i:])
+	}
+	g := Analyze(mem, j1.J1)
+	cold, ok := g.Word("cold")
+	if !ok || !cold.Reachable {
+		t.Fatalf("cold: got %v", cold)
+	}
+	if reset := g.Words[0]; !reflect.DeepEqual(reset.Calls, []uint16{cold.Addr}) {
+		t.Errorf("reset calls %v, want %0.4X", reset.Calls, cold.Addr)
+	}
+	// strings and loop arguments are not taken for code
+	for _, name := range []string{"fill", ".ok", "dump"} {
+		w, ok := g.Word(name)
+		if !ok {
+			t.Fatalf("%v: not found", name)
+		}
+		for _, c := range w.Calls {
+			if _, ok := g.byAddr[c>>1]; !ok || c < 0x80 {
+				t.Errorf("%v calls %0.4X", name, c)
+			}
+		}
+	}
+	if _, ok := g.Word("words"); ok && len(g.Unreachable()) == 0 {
+		t.Error("interpreter words are reached only through dictionary")
+	}
+	var b bytes.Buffer
+	if err := g.WriteCFG(&b, "?dup"); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`label="?dup"`, "b04D4 -> b04DA;", "b04D4 -> b04D8;"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("cfg lacks %v", s)
+		}
+	}
+	b.Reset()
+	if err := g.WriteCalls(&b); err != nil {
+		t.Fatal(err)
+	}
+	if s := "w0000 -> w19D4;"; !strings.Contains(b.String(), s) {
+		t.Errorf("calls lack %v", s)
+	}
+	b.Reset()
+	if err := g.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var got Graph
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Words) != len(g.Words) {
+		t.Errorf("json: got %v words, want %v", len(got.Words), len(g.Words))
+	}
+}
+
+func TestEffect(t *testing.T) {
+	var (
+		dup   = j1.ALU{Opcode: j1.OpT, TtoN: true, Ddir: 1}
+		drop  = j1.ALU{Opcode: j1.OpN, Ddir: -1}
+		plus  = j1.ALU{Opcode: j1.OpTplusN, Ddir: -1}
+		toR   = j1.ALU{Opcode: j1.OpN, TtoR: true, Ddir: -1, Rdir: 1}
+		ret   = j1.ALU{Opcode: j1.OpT, RtoPC: true, Rdir: -1}
+		fromR = j1.ALU{Opcode: j1.OpR, TtoN: true, Ddir: 1, Rdir: -1}
+		rput  = j1.ALU{Opcode: j1.OpT, TtoR: true}
+	)
+	mem := encode(
+		j1.Jump(0),        // 0000 reset
+		dup,               // 0002 f: dup + ;
+		plus,              // 0004
+		ret,               // 0006
+		j1.Call(1),        // 0008 g: f 1 ;
+		j1.Literal(1),     // 000A
+		ret,               // 000C
+		j1.Conditional(9), // 000E h: if drop then ;
+		drop,              // 0010
+		ret,               // 0012
+		toR,               // 0014 k: >r ;
+		ret,               // 0016
+		j1.Jump(1),        // 0018 m: f ; tail call
+		fromR,             // 001A n: r> T→R ;
+		rput,              // 001C
+		ret,               // 001E
+	)
+	g := Analyze(mem, j1.J1, 0x02, 0x08, 0x0e, 0x14, 0x18, 0x1a)
+	testCases := []struct {
+		addr    uint16
+		want    string
+		problem bool
+	}{
+		{addr: 0x02, want: "( 1 -- 1 )"},
+		{addr: 0x08, want: "( 1 -- 2 )"},
+		{addr: 0x0e, want: "( ? )", problem: true},
+		{addr: 0x14, want: "( ? )", problem: true},
+		{addr: 0x18, want: "( 1 -- 1 )"},
+		{addr: 0x1a, want: "( 0 -- 1 ) ( R: 1 -- 0 )"},
+	}
+	for _, tc := range testCases {
+		w := g.byAddr[tc.addr>>1]
+		if got := w.Effect.String(); got != tc.want {
+			t.Errorf("%v: got %v, want %v", w, got, tc.want)
+		}
+		if got := len(w.Effect.Problems) > 0; got != tc.problem {
+			t.Errorf("%v: problems %v", w, w.Effect.Problems)
+		}
+	}
+}
+
+func TestEForthEffect(t *testing.T) {
+	mem := make([]uint16, len(eforth.Image)/2)
+	for i := range mem {
+		mem[i] = binary.LittleEndian.Uint16(eforth.Image[2*i:])
+	}
+	g := Analyze(mem, j1.J1)
+	testCases := []struct {
+		name, want string
+		problem    bool
+	}{
+		{name: "+", want: "( 2 -- 1 )"},
+		{name: "dup", want: "( 1 -- 2 )"},
+		{name: "drop", want: "( 1 -- 0 )"},
+		{name: "swap", want: "( 2 -- 2 )"},
+		{name: "over", want: "( 2 -- 3 )"},
+		{name: ">r", want: "( 1 -- 0 ) ( R: 0 -- 1 )"},
+		{name: "r>", want: "( 0 -- 1 ) ( R: 1 -- 0 )"},
+		{name: "!", want: "( 2 -- 0 )"},
+		{name: "max", want: "( 2 -- 1 )"},
+		{name: "count", want: "( 1 -- 2 )"},
+		{name: "fill", want: "( 3 -- 0 )"},
+		{name: "um/mod", want: "( 3 -- 2 )"},
+		{name: "base", want: "( 0 -- 1 )"},
+		{name: "?dup", want: "( ? )", problem: true},
+		{name: "execute", want: "( ? )", problem: true},
+		{name: "dovar", want: "( ? )"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, ok := g.Word(tc.name)
+			if !ok {
+				t.Fatal("not found")
+			}
+			if got := w.Effect.String(); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+			if got := len(w.Effect.Problems) > 0; got != tc.problem {
+				t.Errorf("problems %v", w.Effect.Problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package cfg
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WriteJSON of whole graph
+func (g *Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(g)
+}
+
+// WriteCalls writes call graph in Graphviz DOT. Unreachable words are
+// dashed, references by literal are dotted edges.
+func (g *Graph) WriteCalls(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "digraph calls {")
+	fmt.Fprintln(b, "\tnode [shape=box];")
+	for _, v := range g.Words {
+		style := ""
+		if !v.Reachable {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(b, "\tw%0.4X [label=%q%s];\n", v.Addr, v.Name, style)
+	}
+	for _, v := range g.Words {
+		for _, c := range v.Calls {
+			fmt.Fprintf(b, "\tw%0.4X -> w%0.4X;\n", v.Addr, c)
+		}
+		for _, r := range v.Refs {
+			fmt.Fprintf(b, "\tw%0.4X -> w%0.4X [style=dotted];\n", v.Addr, r)
+		}
+	}
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
+
+// WriteCFG writes control flow graphs of named words, or of all words, in
+// Graphviz DOT. Each word is a cluster of its blocks, calls leave it.
+func (g *Graph) WriteCFG(w io.Writer, names ...string) error {
+	words := g.Words
+	if len(names) > 0 {
+		words = nil
+		for _, name := range names {
+			v, ok := g.Word(name)
+			if !ok {
+				return fmt.Errorf("%v: word not found", name)
+			}
+			words = append(words, v)
+		}
+	}
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "digraph cfg {")
+	fmt.Fprintln(b, "\tnode [shape=box];")
+	for _, v := range words {
+		fmt.Fprintf(b, "\tsubgraph cluster_%0.4X {\n", v.Addr)
+		fmt.Fprintf(b, "\t\tlabel=%q;\n", v.Name)
+		for _, blk := range v.Blocks {
+			fmt.Fprintf(b, "\t\tb%0.4X [label=\"%0.4X-%0.4X\"];\n", blk.Addr, blk.Addr, blk.End)
+		}
+		fmt.Fprintln(b, "\t}")
+		for _, blk := range v.Blocks {
+			for _, s := range blk.Succ {
+				fmt.Fprintf(b, "\tb%0.4X -> b%0.4X;\n", blk.Addr, s)
+			}
+			for _, c := range blk.Calls {
+				name := fmt.Sprintf("%0.4X", c)
+				if u, ok := g.byAddr[c>>1]; ok {
+					name = u.Name
+				}
+				fmt.Fprintf(b, "\tc%0.4X_%0.4X [label=%q, shape=ellipse];\n", blk.Addr, c, name)
+				fmt.Fprintf(b, "\tb%0.4X -> c%0.4X_%0.4X [style=dashed];\n", blk.Addr, blk.Addr, c)
+			}
+		}
+	}
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
@@ -0,0 +1,355 @@
+package cfg
+
+import (
+	"fmt"
+
+	"github.com/dim13/j1"
+)
+
+// Effect of word on data and return stack, in cells
+type Effect struct {
+	In       int      `json:"in"`
+	Out      int      `json:"out"`
+	RIn      int      `json:"rin,omitempty"`
+	ROut     int      `json:"rout,omitempty"`
+	Known    bool     `json:"known"`              // false if it depends on unknown code
+	Problems []string `json:"problems,omitempty"` // branches disagree or return stack is off
+}
+
+func (e Effect) String() string {
+	if !e.Known {
+		return "( ? )"
+	}
+	s := fmt.Sprintf("( %d -- %d )", e.In, e.Out)
+	if e.RIn != 0 || e.ROut != 0 {
+		s += fmt.Sprintf(" ( R: %d -- %d )", e.RIn, e.ROut)
+	}
+	return s
+}
+
+// readsN is true for opcodes taking N
+func readsN(op j1.Op) bool {
+	switch op {
+	case j1.OpT, j1.OpNotT, j1.OpTminus1, j1.OpR, j1.OpAtT, j1.OpDepth, j1.OpIoAtT:
+		return false
+	}
+	return true
+}
+
+// sym is abstract stack value: inputs are negative, computed ones positive
+type sym int
+
+const (
+	rbase = -1 << 16 // return stack inputs, ret is first one
+	ret   = rbase - 1
+)
+
+// state of abstract interpretation, stacks bottom first
+type state struct {
+	d, r     []sym
+	din, rin int // inputs taken from caller
+	fresh    *sym
+	used     map[sym]bool // operands, shared by all paths
+}
+
+func (s state) clone() state {
+	s.d = append([]sym(nil), s.d...)
+	s.r = append([]sym(nil), s.r...)
+	return s
+}
+
+func (s *state) new() sym {
+	*s.fresh++
+	return *s.fresh
+}
+
+// need k cells on data stack, taking them from caller
+func (s *state) need(k int) {
+	for len(s.d) < k {
+		s.din++
+		s.d = append([]sym{sym(-s.din)}, s.d...)
+	}
+}
+
+// rneed k cells on return stack, taking them from caller
+func (s *state) rneed(k int) {
+	for len(s.r) < k {
+		s.rin++
+		s.r = append([]sym{sym(rbase - s.rin)}, s.r...)
+	}
+}
+
+func (s *state) push(v sym) { s.d = append(s.d, v) }
+
+func (s *state) pop() sym {
+	s.need(1)
+	v := s.d[len(s.d)-1]
+	s.d = s.d[:len(s.d)-1]
+	s.used[v] = true
+	return v
+}
+
+// depth of stacks relative to entry
+func (s state) depth() [2]int { return [2]int{len(s.d) - s.din, len(s.r) - s.rin} }
+
+// alu executes instruction as Core.Execute does. It returns top of return
+// stack as seen by R→PC.
+func (s *state) alu(v j1.ALU) sym {
+	dn := 1
+	if readsN(v.Opcode) || v.NtoAtT || v.NtoIoAtT || (v.TtoN && v.Ddir <= 0) {
+		dn = 2
+	}
+	if k := 1 - int(v.Ddir); k > dn {
+		dn = k
+	}
+	if v.TtoN && v.Ddir < 0 {
+		dn = 2 - int(v.Ddir)
+	}
+	s.need(dn)
+	rn := 0
+	if v.Opcode == j1.OpR || v.RtoPC {
+		rn = 1
+	}
+	if k := -int(v.Rdir); k > rn {
+		rn = k
+	}
+	if v.TtoR && v.Rdir <= 0 {
+		rn = 1 - int(v.Rdir)
+	}
+	s.rneed(rn)
+
+	t := s.d[len(s.d)-1]
+	var top sym
+	if len(s.r) > 0 {
+		top = s.r[len(s.r)-1]
+	}
+	nt := s.new()
+	switch v.Opcode {
+	case j1.OpT:
+		nt = t
+	case j1.OpN:
+		nt = s.d[len(s.d)-2]
+	case j1.OpR:
+		nt = top
+	case j1.OpDepth:
+	default:
+		s.used[t] = true
+	}
+	if (readsN(v.Opcode) && v.Opcode != j1.OpN) || v.NtoAtT || v.NtoIoAtT {
+		s.used[t] = true
+		s.used[s.d[len(s.d)-2]] = true
+	}
+	ds := s.d[:len(s.d)-1]
+	switch {
+	case v.Ddir > 0:
+		ds = append(ds, s.new())
+	case v.Ddir < 0:
+		ds = ds[:len(ds)+int(v.Ddir)]
+	}
+	if v.TtoN {
+		ds[len(ds)-1] = t
+	}
+	s.d = append(ds, nt)
+	switch {
+	case v.Rdir > 0:
+		s.r = append(s.r, s.new())
+	case v.Rdir < 0:
+		s.r = s.r[:len(s.r)+int(v.Rdir)]
+	}
+	if v.TtoR {
+		s.r[len(s.r)-1] = t
+	}
+	return top
+}
+
+// call applies effect of callee
+func (s *state) call(e *Effect) {
+	s.need(e.In)
+	for _, v := range s.d[len(s.d)-e.In:] {
+		s.used[v] = true
+	}
+	s.d = s.d[:len(s.d)-e.In]
+	for i := 0; i < e.Out; i++ {
+		s.push(s.new())
+	}
+	s.rneed(e.RIn)
+	s.r = s.r[:len(s.r)-e.RIn]
+	for i := 0; i < e.ROut; i++ {
+		s.r = append(s.r, s.new())
+	}
+}
+
+// kept counts inputs left in place at bottom of stack, neither used nor
+// copied
+func (s state) kept(stack []sym, inputs int, input func(int) sym) int {
+	count := make(map[sym]int)
+	for _, v := range stack {
+		count[v]++
+	}
+	k := 0
+	for k < len(stack) && k < inputs {
+		v := input(inputs - k)
+		if stack[k] != v || s.used[v] || count[v] > 1 {
+			break
+		}
+		k++
+	}
+	return k
+}
+
+// effect of path ending in return, inputs left in place are not counted
+func (s state) effect() Effect {
+	kd := s.kept(s.d, s.din, func(i int) sym { return sym(-i) })
+	// ret is popped already, it was first of return stack inputs
+	kr := s.kept(s.r, s.rin-1, func(i int) sym { return sym(rbase - 1 - i) })
+	return Effect{
+		Known: true,
+		In:    s.din - kd,
+		Out:   len(s.d) - kd,
+		RIn:   s.rin - 1 - kr,
+		ROut:  len(s.r) - kr,
+	}
+}
+
+// steps bound abstract interpretation of a word
+const steps = 1 << 14
+
+// infer effects of all words
+func (g *Graph) infer() {
+	for _, w := range g.Words {
+		g.effect(w)
+	}
+}
+
+// effect of word, computed once. Recursive words are unknown.
+func (g *Graph) effect(w *Word) *Effect {
+	if w.Effect != nil {
+		return w.Effect
+	}
+	w.Effect = &Effect{}
+	if g.runtime[w.Addr>>1] || len(w.Blocks) == 0 {
+		return w.Effect
+	}
+	blocks := make(map[uint16]Block)
+	for _, b := range w.Blocks {
+		blocks[b.Addr>>1] = b
+	}
+	e := &Effect{Known: true}
+	problem := func(format string, args ...interface{}) {
+		e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+	}
+	type visit struct {
+		depth    [2]int
+		din, rin int
+	}
+	seen := make(map[uint16]visit)
+	var exits []Effect
+	// exit checks that word returns to its caller
+	exit := func(s state, at uint16, top sym) {
+		if top != ret {
+			problem("%0.4X: return stack unbalanced", at<<1)
+			return
+		}
+		exits = append(exits, s.effect())
+	}
+	var fresh sym
+	work := []struct {
+		pc uint16
+		s  state
+	}{{w.Addr >> 1, state{r: []sym{ret}, rin: 1, fresh: &fresh, used: make(map[sym]bool)}}}
+	for n := 0; len(work) > 0 && e.Known; n++ {
+		if n > steps {
+			e.Known = false
+			break
+		}
+		pc, s := work[len(work)-1].pc, work[len(work)-1].s
+		work = work[:len(work)-1]
+		b, ok := blocks[pc]
+		if !ok {
+			e.Known = false
+			break
+		}
+		if v, ok := seen[pc]; ok {
+			if v.depth != s.depth() {
+				problem("%0.4X: stack depth differs across branches", pc<<1)
+				continue
+			}
+			if s.din <= v.din && s.rin <= v.rin {
+				continue
+			}
+		}
+		seen[pc] = visit{depth: s.depth(), din: s.din, rin: s.rin}
+		last := b.End>>1 - 1
+		var callee uint16
+		for ; pc <= last; pc++ {
+			switch v := g.isa.Decode(g.mem[pc]).(type) {
+			case j1.Literal:
+				s.push(s.new())
+			case j1.Conditional:
+				s.pop()
+			case j1.Call:
+				callee = uint16(v) << 1
+				c, ok := g.byAddr[uint16(v)]
+				if !ok || !g.effect(c).Known {
+					e.Known = false
+					break
+				}
+				s.call(c.Effect)
+			case j1.ALU:
+				if top := s.alu(v); v.RtoPC {
+					if v.Rdir != -1 {
+						top = 0
+					}
+					exit(s, pc, top)
+				}
+			}
+		}
+		for _, t := range b.Calls {
+			if t == callee {
+				continue
+			}
+			// tail call or fall through, returns on behalf of word
+			c, ok := g.byAddr[t>>1]
+			if !ok || !g.effect(c).Known {
+				e.Known = false
+				break
+			}
+			ts := s.clone()
+			ts.call(c.Effect)
+			ts.rneed(1)
+			top := ts.r[len(ts.r)-1]
+			ts.r = ts.r[:len(ts.r)-1]
+			exit(ts, last, top)
+		}
+		for _, t := range b.Succ {
+			work = append(work, struct {
+				pc uint16
+				s  state
+			}{t >> 1, s.clone()})
+		}
+	}
+	if len(exits) == 0 {
+		e.Known = false
+	}
+	for _, x := range exits {
+		if x.Out-x.In != exits[0].Out-exits[0].In || x.ROut-x.RIn != exits[0].ROut-exits[0].RIn {
+			problem("stack effect differs across branches: %v and %v", exits[0], x)
+			break
+		}
+		if x.In > e.In {
+			e.In = x.In
+		}
+		if x.RIn > e.RIn {
+			e.RIn = x.RIn
+		}
+	}
+	if len(exits) > 0 {
+		e.Out = e.In + exits[0].Out - exits[0].In
+		e.ROut = e.RIn + exits[0].ROut - exits[0].RIn
+	}
+	if len(e.Problems) > 0 {
+		e.Known = false
+	}
+	w.Effect = e
+	return e
+}
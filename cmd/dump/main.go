@@ -1,28 +1,115 @@
 package main
 
 import (
+	"bytes"
 	"encoding/binary"
+	"flag"
 	"fmt"
 	"os"
 
 	"github.com/dim13/j1"
+	"github.com/dim13/j1/cfg"
+	"github.com/dim13/j1/eforth"
+	"github.com/dim13/j1/loader"
+	"github.com/dim13/j1/see"
 )
 
 func main() {
-	body, err := ReadBin("testdata/j1e.bin")
+	image := flag.String("image", "testdata/j1e.bin", "memory image")
+	word := flag.String("see", "", "decompile word instead of dump")
+	graph := flag.String("graph", "", "write calls or cfg of words given as arguments in DOT, or json")
+	unreachable := flag.Bool("unreachable", false, "list words not reached from reset vector")
+	effects := flag.Bool("effects", false, "list stack effects of words and their problems")
+	flag.Parse()
+
+	body, err := ReadBin(*image)
+	if err != nil {
+		panic(err)
+	}
+	if *graph != "" || *unreachable || *effects {
+		g := cfg.Analyze(body, j1.J1)
+		switch *graph {
+		case "calls":
+			err = g.WriteCalls(os.Stdout)
+		case "cfg":
+			err = g.WriteCFG(os.Stdout, flag.Args()...)
+		case "json":
+			err = g.WriteJSON(os.Stdout)
+		case "":
+			if *effects {
+				for _, w := range g.Words {
+					fmt.Printf("%v %v\n", w, w.Effect)
+					for _, p := range w.Effect.Problems {
+						fmt.Printf("\t%v\n", p)
+					}
+				}
+				break
+			}
+			for _, w := range g.Unreachable() {
+				fmt.Println(w)
+			}
+		default:
+			err = fmt.Errorf("%v: unknown graph", *graph)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *word != "" {
+		d, err := see.New(body, j1.J1)
+		if err != nil {
+			panic(err)
+		}
+		s, err := d.See(*word)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(s)
+		return
+	}
+	dict, err := eforth.NewDictionary(body)
 	if err != nil {
 		panic(err)
 	}
 	for i, v := range body {
+		if w, ok := dict.At(uint16(2 * i)); ok {
+			fmt.Printf("\\ %s", w.Name)
+			if w.Flags != 0 {
+				fmt.Printf(" (%v)", w.Flags)
+			}
+			fmt.Println()
+		}
 		hi, lo := ascii(uint8(v>>8)), ascii(uint8(v))
 		ins := j1.Decode(v)
-		fmt.Printf("%0.4X %0.4X [%c%c]\t%s\n", 2*i, v, lo, hi, ins)
+		fmt.Printf("%0.4X %0.4X [%c%c]\t%s%s\n", 2*i, v, lo, hi, ins, target(dict, ins))
 		if alu, ok := ins.(j1.ALU); ok && alu.RtoPC {
 			fmt.Printf("\n")
 		}
 	}
 }
 
+// target names word called or jumped to
+func target(dict *eforth.Dictionary, ins j1.Instruction) string {
+	var addr uint16
+	switch v := ins.(type) {
+	case j1.Call:
+		addr = uint16(v)
+	case j1.Jump:
+		addr = uint16(v)
+	case j1.Conditional:
+		addr = uint16(v)
+	default:
+		return ""
+	}
+	if w, ok := dict.At(addr << 1); ok {
+		return "\t" + w.Name
+	}
+	return ""
+}
+
 func ascii(x uint8) uint8 {
 	if x >= 0x20 && x < 0x7f {
 		return x
@@ -30,20 +117,14 @@ func ascii(x uint8) uint8 {
 	return 0x20
 }
 
-// ReadBin file
+// ReadBin file in any format known to loader
 func ReadBin(fname string) ([]uint16, error) {
-	fd, err := os.Open(fname)
-	if err != nil {
-		return nil, err
-	}
-	defer fd.Close()
-	stat, err := fd.Stat()
+	data, err := loader.Load(fname, loader.Auto)
 	if err != nil {
 		return nil, err
 	}
-	size := stat.Size()
-	body := make([]uint16, int(size)/2)
-	if err := binary.Read(fd, binary.LittleEndian, &body); err != nil {
+	body := make([]uint16, len(data)/2)
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &body); err != nil {
 		return nil, err
 	}
 	return body, nil
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/dim13/j1/cross"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "base directory of source files")
+	bin := flag.String("bin", "", "write little-endian memory image to file")
+	lst := flag.String("lst", "", "write listing to file")
+	opt := flag.Bool("O", false, "optimize target definitions")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] file.fs...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	c := cross.NewCompiler()
+	c.Dir = *dir
+	c.Optimize = *opt
+	for _, name := range flag.Args() {
+		if err := c.Include(name); err != nil {
+			if errors.Is(err, cross.ErrBye) {
+				break
+			}
+			log.Fatal(err)
+		}
+	}
+	if *bin != "" {
+		if err := os.WriteFile(*bin, c.Image(), 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *lst != "" {
+		f, err := os.Create(*lst)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		if err := c.WriteListing(f); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -2,18 +2,205 @@ package main
 
 import (
 	"context"
-	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
 
 	"github.com/dim13/j1"
+	"github.com/dim13/j1/board"
 	"github.com/dim13/j1/console"
+	"github.com/dim13/j1/device"
+	"github.com/dim13/j1/eforth"
+	"github.com/dim13/j1/loader"
 )
 
-//go:embed j1e.bin
-var eForth []byte
+// exit codes
+const (
+	exitError  = 1 // bad usage, image or fault
+	exitBudget = 2 // instruction budget exhausted
+	exitAbort  = 3 // eForth reported errors in batch mode
+)
+
+var errBudget = errors.New("instruction budget exhausted")
+
+// idleCycles of silent serial line end j1demo runs in batch mode, two
+// seconds at 33 MHz, longer than the firmware sleeps while booting
+const idleCycles = 2 * 33000000
 
 func main() {
-	ctx, con := console.New(context.Background())
+	root := flag.String("root", ".", "host directory accessible by include")
+	blocks := flag.String("blocks", "", "host file backing Forth blocks")
+	imageFile := flag.String("image", "", "memory image, embedded eForth if empty")
+	formatName := flag.String("format", "auto", "image format: auto, bin, mem or ihex")
+	isaName := flag.String("isa", "j1", "instruction set: j1 or j1b")
+	boardName := flag.String("board", "eforth", "peripherals: eforth, or j1demo of docs/j1demo")
+	trace := flag.Bool("trace", false, "trace instructions to stderr")
+	budget := flag.Uint64("budget", 0, "stop after this many instructions, 0 for no limit")
+	batch := flag.Bool("batch", false, "run without reading stdin, exit once eForth waits for input or j1demo serial is idle")
+	var inputs []io.Reader
+	var opened []*os.File
+	flag.Func("e", "evaluate words in batch mode, may be repeated", func(s string) error {
+		inputs = append(inputs, strings.NewReader(s+"\n"))
+		return nil
+	})
+	flag.Func("f", "evaluate file in batch mode, - for stdin, may be repeated", func(s string) error {
+		if s == "-" {
+			inputs = append(inputs, os.Stdin)
+			return nil
+		}
+		f, err := os.Open(s)
+		if err != nil {
+			return err
+		}
+		inputs, opened = append(inputs, f), append(opened, f)
+		return nil
+	})
+	var watches []j1.Watchpoint
+	flag.Func("watch", "report memory access, as in 'write 0x1a06 if V > 0x2000', may be repeated", func(s string) error {
+		w, err := j1.ParseWatchpoint(s)
+		if err != nil {
+			return err
+		}
+		watches = append(watches, w)
+		return nil
+	})
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [file.fs]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+	closeInputs := func() {
+		for _, f := range opened {
+			f.Close()
+		}
+	}
+	defer closeInputs()
+
+	image := eforth.Image
+	if *imageFile != "" {
+		format, err := loader.ParseFormat(*formatName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if image, err = loader.Load(*imageFile, format); err != nil {
+			log.Fatal(err)
+		}
+	}
+	isa, err := j1.ParseISA(*isaName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *boardName != "eforth" && *boardName != "j1demo" {
+		log.Fatalf("unknown board %q", *boardName)
+	}
+	var script []byte
+	if *boardName == "eforth" && *imageFile == "" {
+		// include at hand, before script of user
+		script = []byte(device.HostFSWords)
+	}
+	if flag.NArg() > 0 {
+		user, err := os.ReadFile(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		script = append(script, user...)
+	}
+
+	ctx := context.Background()
+	var con j1.Console
+	var batchCon *console.Batch
+	if *batch || len(inputs) > 0 {
+		ctx, batchCon = console.NewBatch(ctx, io.MultiReader(inputs...), os.Stdout)
+		con = batchCon
+	} else {
+		ctx, con = console.New(ctx)
+	}
 	vm := j1.New(con)
-	vm.Write(eForth)
-	vm.Run(ctx)
+	vm.SetISA(isa)
+	for _, w := range watches {
+		if err := vm.Watch(w); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if _, err := vm.Write(image); err != nil {
+		log.Fatal(err)
+	}
+	if script != nil {
+		if err := vm.Boot(script); err != nil {
+			log.Fatal(err)
+		}
+	}
+	files := device.NewHostFS(*root)
+	defer files.Close()
+	var idle func() bool
+	if *boardName == "j1demo" {
+		demo := board.NewJ1Demo(vm, con)
+		if batchCon != nil {
+			idle = func() bool { return demo.Idle() > idleCycles }
+		}
+	} else {
+		vm.Attach(0x7010, device.FileSize, files)
+		if *blocks != "" {
+			f, err := os.OpenFile(*blocks, os.O_RDWR|os.O_CREATE, 0644)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			vm.Attach(0x7020, device.BlockSize, device.NewBlock(f))
+		}
+		vm.Attach(0x7030, device.TimerSize, device.NewTimer(vm.Cycles))
+	}
+
+	if err := run(ctx, vm, isa, *budget, *trace, idle); err != nil {
+		fmt.Fprintln(os.Stderr)
+		log.Println(err)
+		files.Close()
+		closeInputs()
+		if errors.Is(err, errBudget) {
+			os.Exit(exitBudget)
+		}
+		os.Exit(exitError)
+	}
+	if batchCon != nil && batchCon.Aborts() > 0 {
+		files.Close()
+		closeInputs()
+		os.Exit(exitAbort)
+	}
+}
+
+// run core until fault or cancellation, or until budget is exhausted or
+// idle returns true if given
+func run(ctx context.Context, vm *j1.Core, isa j1.ISA, budget uint64, trace bool, idle func() bool) error {
+	if budget == 0 && !trace && idle == nil {
+		for {
+			err := vm.Run(ctx)
+			var hit *j1.WatchHit
+			if !errors.As(err, &hit) {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, hit)
+		}
+	}
+	for n := uint64(0); ctx.Err() == nil && vm.Err() == nil; n++ {
+		if budget > 0 && n == budget {
+			return errBudget
+		}
+		if idle != nil && idle() {
+			return nil
+		}
+		pc, ins := vm.PC(), vm.Fetch()
+		if trace {
+			fmt.Fprintf(os.Stderr, "%0.4X %0.4X\t%v\n", pc<<1, isa.Encode(ins), ins)
+		}
+		vm.Execute(ins)
+		if hit := vm.Hit(); hit != nil {
+			fmt.Fprintln(os.Stderr, hit)
+		}
+	}
+	return vm.Err()
 }
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+
+	"github.com/dim13/j1/eforth"
+	"github.com/dim13/j1/telnet"
+)
+
+func main() {
+	addr := flag.String("addr", ":2323", "listen address")
+	flag.Parse()
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("listening on", l.Addr())
+	log.Fatal(telnet.Serve(context.Background(), l, eforth.Image))
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/dim13/j1/eforth"
+	"github.com/dim13/j1/web"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	flag.Parse()
+	log.Println("listening on", *addr)
+	log.Fatal(http.ListenAndServe(*addr, web.Handler(eforth.Image)))
+}
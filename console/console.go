@@ -1,9 +1,11 @@
 package console
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // Console I/O
@@ -59,3 +61,69 @@ func (c *Console) Len() uint16 {
 func (c *Console) Stop() {
 	c.cancel()
 }
+
+// Batch console feeds input from a reader and writes output to a writer.
+// It stops the core once input is exhausted and eForth polls for more.
+type Batch struct {
+	r      *bufio.Reader
+	w      io.Writer
+	idle   int     // polls of empty input without output in between
+	tail   [3]byte // recent output
+	aborts int
+	cancel func()
+}
+
+// abortMarker ends eForth error messages, like " foo?" of unknown word
+const abortMarker = "?\r\n"
+
+// NewBatch console, r may be nil for no input
+func NewBatch(ctx context.Context, r io.Reader, w io.Writer) (context.Context, *Batch) {
+	ctx, cancel := context.WithCancel(ctx)
+	if r == nil {
+		r = strings.NewReader("")
+	}
+	return ctx, &Batch{r: bufio.NewReader(r), w: w, cancel: cancel}
+}
+
+// Read from console
+func (c *Batch) Read() uint16 {
+	v, err := c.r.ReadByte()
+	if err != nil {
+		return 0
+	}
+	return uint16(v)
+}
+
+// Write to console
+func (c *Batch) Write(v uint16) {
+	c.idle = 0
+	c.tail[0], c.tail[1], c.tail[2] = c.tail[1], c.tail[2], byte(v)
+	if string(c.tail[:]) == abortMarker {
+		c.aborts++
+	}
+	if _, err := c.w.Write([]byte{byte(v)}); err != nil {
+		c.cancel()
+	}
+}
+
+// Len of input buffer, stops core when it polls empty input twice
+func (c *Batch) Len() uint16 {
+	if _, err := c.r.Peek(1); err == nil {
+		c.idle = 0
+		return 1
+	}
+	if c.idle++; c.idle > 1 {
+		c.cancel()
+	}
+	return 0
+}
+
+// Stop console
+func (c *Batch) Stop() {
+	c.cancel()
+}
+
+// Aborts counts error messages seen in output
+func (c *Batch) Aborts() int {
+	return c.aborts
+}
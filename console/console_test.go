@@ -0,0 +1,47 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dim13/j1"
+	"github.com/dim13/j1/eforth"
+)
+
+func TestBatch(t *testing.T) {
+	testCases := []struct {
+		in     string
+		out    string
+		aborts int
+	}{
+		{in: "1 2 + .\n", out: "1 2 + . 3 ok", aborts: 0},
+		{in: "nosuch\n1 .\n", out: "nosuch nosuch?\r\n ok\r\n1 . 1 ok", aborts: 1},
+		{in: "2 . bye\n3 .\n", out: "2 . bye 2", aborts: 0},
+		{in: "", out: "eforth j1 v1.04\r\n", aborts: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			var out bytes.Buffer
+			ctx, con := NewBatch(ctx, strings.NewReader(tc.in), &out)
+			vm := j1.New(con)
+			if _, err := vm.Write(eforth.Image); err != nil {
+				t.Fatal(err)
+			}
+			vm.Run(ctx)
+			if ctx.Err() != context.Canceled {
+				t.Fatalf("got %v, want %v", ctx.Err(), context.Canceled)
+			}
+			if !strings.Contains(out.String(), tc.out) {
+				t.Errorf("got %q, want %q", out.String(), tc.out)
+			}
+			if v := con.Aborts(); v != tc.aborts {
+				t.Errorf("aborts: got %v, want %v", v, tc.aborts)
+			}
+		})
+	}
+}
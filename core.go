@@ -15,6 +15,17 @@ type Console interface {
 	Stop()
 }
 
+// Device is a memory-mapped peripheral, addresses are relative to its base
+type Device interface {
+	Read(addr uint16) uint16
+	Write(addr, value uint16)
+}
+
+type mapping struct {
+	base, size uint16
+	dev        Device
+}
+
 // Core of J1 Forth CPU
 //
 //	33 deep × 16 bit data stack
@@ -23,11 +34,21 @@ type Console interface {
 //	memory is 16 bit wide and addressed by bytes
 //	0..0x3fff RAM, 0x4000..0x7fff mem-mapped I/O
 type Core struct {
-	memory  [8192]uint16 // 0..0x3fff RAM, 0x4000..0x7fff mem-mapped I/O
-	pc      uint16       // 13 bit
-	st0     uint16       // top of data stack
-	d, r    stack        // data and return stacks
-	console Console      // console i/o
+	memory  [8192]uint16  // 0..0x3fff RAM, 0x4000..0x7fff mem-mapped I/O
+	pc      uint16        // 13 bit
+	st0     uint16        // top of data stack
+	d, r    stack         // data and return stacks
+	console Console       // console i/o
+	devices []mapping     // mem-mapped peripherals
+	cycles  uint64        // executed instructions
+	isa     ISA           // instruction set variant
+	code    [8192]decoded // predecoded memory, see run
+	io      bool          // i/o accessed or fault, Run checks for cancellation
+	fault   *Fault        // stops execution, see Err
+	prot    *[8192]Perm   // memory protection, nil if off
+	watches []watchpoint  // memory watchpoints
+	hit     *WatchHit     // watchpoint triggered, stops Run
+	peers   []*Core       // sharing memory, see System
 }
 
 // New core with console i/o
@@ -35,17 +56,98 @@ func New(con Console) *Core {
 	return &Core{console: con}
 }
 
+// Attach device to I/O address range base..base+size-1.
+// Console addresses take precedence over devices.
+func (c *Core) Attach(base, size uint16, dev Device) {
+	c.devices = append(c.devices, mapping{base: base, size: size, dev: dev})
+}
+
+func (c *Core) device(addr uint16) (Device, uint16) {
+	for _, m := range c.devices {
+		if addr-m.base < m.size {
+			return m.dev, addr - m.base
+		}
+	}
+	return nil, 0
+}
+
+// rom is read-only memory device
+type rom []uint16
+
+func (r rom) Read(addr uint16) uint16 {
+	if i := int(addr >> 1); i < len(r) {
+		return r[i]
+	}
+	return 0
+}
+
+func (r rom) Write(addr, value uint16) {}
+
+// maxBoot is room for boot script between 0x4000 and console at 0x7000
+const maxBoot = 0x3000 - 2
+
+// Boot maps script to I/O address 0x4000 the way j1.c does: size in the first
+// cell, content in the following ones. eForth evaluates it on cold start.
+// Booting again replaces the script.
+func (c *Core) Boot(script []byte) error {
+	if len(script) > maxBoot {
+		return fmt.Errorf("boot script size %v > %v", len(script), maxBoot)
+	}
+	r := make(rom, 1+(len(script)+1)/2)
+	r[0] = uint16(len(script))
+	for i, v := range script {
+		r[1+i/2] |= uint16(v) << (8 * (i % 2))
+	}
+	m := mapping{base: 0x4000, size: uint16(2 * len(r)), dev: r}
+	for i, d := range c.devices {
+		if _, ok := d.dev.(rom); ok && d.base == m.base {
+			c.devices[i] = m
+			return nil
+		}
+	}
+	c.devices = append(c.devices, m)
+	return nil
+}
+
+// SetISA selects instruction set variant, J1 by default
+func (c *Core) SetISA(isa ISA) {
+	c.isa = isa
+	c.flush()
+}
+
+// PC is current program counter, in cells
+func (c *Core) PC() uint16 {
+	return c.pc
+}
+
+// Cycles executed so far, every instruction takes one cycle
+func (c *Core) Cycles() uint64 {
+	return c.cycles
+}
+
+// Memory returns copy of memory cells, e.g. for eforth.Words
+func (c *Core) Memory() []uint16 {
+	m := make([]uint16, len(c.memory))
+	copy(m, c.memory[:])
+	return m
+}
+
 // Reset VM
 func (c *Core) Reset() {
 	c.pc, c.st0, c.d.sp, c.r.sp = 0, 0, 0, 0
+	c.fault = nil
 }
 
-// Write memory
+// Write memory image of little-endian cells
 func (c *Core) Write(data []byte) (int, error) {
 	size := len(data) >> 1
-	if size >= len(c.memory) {
-		return 0, fmt.Errorf("data size %v > memory size %v", size, len(c.memory))
+	if size > len(c.memory) {
+		return 0, &Fault{Addr: uint16(2 * len(c.memory)), Err: ErrImageTooLarge}
+	}
+	if len(data)&1 != 0 {
+		return 0, &Fault{Addr: uint16(len(data) - 1), Err: ErrOddLength}
 	}
+	c.flush()
 	return len(data), binary.Read(bytes.NewReader(data), binary.LittleEndian, c.memory[:size])
 }
 
@@ -58,15 +160,29 @@ func (c *Core) String() string {
 
 const ioMask = 3 << 14
 
+// pcMask keeps program counter in 13 bits
+const pcMask = 1<<13 - 1
+
 func (c *Core) writeAt(addr, value uint16) {
 	if addr&ioMask == 0 {
 		c.memory[addr>>1] = value
+		c.written(addr >> 1)
+		return
 	}
+	c.writeIO(addr, value)
+}
+
+func (c *Core) writeIO(addr, value uint16) {
+	c.io = true
 	switch addr {
 	case 0x7000: // key
 		c.console.Write(value)
 	case 0x7002: // bye
 		c.console.Stop()
+	default:
+		if dev, off := c.device(addr); dev != nil {
+			dev.Write(off, value)
+		}
 	}
 }
 
@@ -74,35 +190,61 @@ func (c *Core) readAt(addr uint16) uint16 {
 	if addr&ioMask == 0 {
 		return c.memory[addr>>1]
 	}
+	return c.readIO(addr)
+}
+
+func (c *Core) readIO(addr uint16) uint16 {
+	c.io = true
 	switch addr {
 	case 0x7000: // tx!
 		return c.console.Read()
 	case 0x7001: // ?rx
 		return c.console.Len()
 	}
+	if dev, off := c.device(addr); dev != nil {
+		return dev.Read(off)
+	}
 	return 0
 }
 
-// Run evaluates content of memory
-func (c *Core) Run(ctx context.Context) {
-	for {
+// Run evaluates content of memory until ctx is done, core faults or a
+// watchpoint triggers. It returns the fault or WatchHit, nil on cancellation.
+// Cancellation is checked after every i/o access and every checkEvery
+// instructions.
+func (c *Core) Run(ctx context.Context) error {
+	done := ctx.Done()
+	c.hit = nil
+	for c.fault == nil && c.hit == nil {
 		select {
-		case <-ctx.Done():
-			return
+		case <-done:
+			return nil
 		default:
-			c.Execute(c.Fetch())
 		}
+		c.run(checkEvery)
+	}
+	if c.hit != nil {
+		return c.hit
 	}
+	return c.fault
 }
 
 // Fetch instruction at current program counter position
 func (c *Core) Fetch() Instruction {
-	return Decode(c.memory[c.pc])
+	return c.isa.Decode(c.memory[c.pc])
 }
 
-// Execute instruction
+// Execute instruction, unless core faulted
 func (c *Core) Execute(ins Instruction) {
-	c.pc++
+	c.hit = nil
+	if c.fault != nil {
+		return
+	}
+	if !c.executable(c.pc) {
+		c.fault = &Fault{Addr: c.pc << 1, Err: ErrNotExecutable}
+		return
+	}
+	c.cycles++
+	c.pc = (c.pc + 1) & pcMask
 	switch v := ins.(type) {
 	case Literal:
 		c.d.push(c.st0)
@@ -118,66 +260,88 @@ func (c *Core) Execute(ins Instruction) {
 		}
 		c.st0 = c.d.pop()
 	case ALU:
-		if v.RtoPC {
-			c.pc = c.r.peek() >> 1
-		}
-		if v.NtoAtT {
-			c.writeAt(c.st0, c.d.peek())
-		}
-		st0 := c.newST0(v.Opcode)
-		c.d.move(v.Ddir)
-		c.r.move(v.Rdir)
-		if v.TtoN {
-			c.d.replace(c.st0)
-		}
-		if v.TtoR {
-			c.r.replace(c.st0)
+		c.alu(&v)
+	}
+}
+
+func (c *Core) alu(v *ALU) {
+	if v.Opcode >= nOps {
+		c.fail(ErrInvalidInstruction)
+		return
+	}
+	if c.guarded() {
+		if err := c.access(v); err != nil {
+			c.fail(err)
+			return
 		}
-		c.st0 = st0
+		c.watch(v)
+	}
+	if v.RtoPC {
+		c.pc = c.r.peek() >> 1 & pcMask
 	}
+	if v.NtoAtT {
+		c.writeAt(c.st0, c.d.peek())
+	}
+	if v.NtoIoAtT {
+		c.writeIO(c.st0, c.d.peek())
+	}
+	st0 := c.newST0(v.Opcode)
+	c.d.move(v.Ddir)
+	c.r.move(v.Rdir)
+	if v.TtoN {
+		c.d.replace(c.st0)
+	}
+	if v.TtoR {
+		c.r.replace(c.st0)
+	}
+	c.st0 = st0
 }
 
-var boolValue = map[bool]uint16{
-	false: 0,
-	true:  ^uint16(0),
+func boolValue(b bool) uint16 {
+	if b {
+		return ^uint16(0)
+	}
+	return 0
 }
 
 func (c *Core) newST0(opcode Op) uint16 {
 	T, N, R := c.st0, c.d.peek(), c.r.peek()
 	switch opcode {
-	case opT: // T
+	case OpT: // T
 		return T
-	case opN: // N
+	case OpN: // N
 		return N
-	case opTplusN: // T+N
+	case OpTplusN: // T+N
 		return T + N
-	case opTandN: // T&N
+	case OpTandN: // T&N
 		return T & N
-	case opTorN: // T|N
+	case OpTorN: // T|N
 		return T | N
-	case opTxorN: // T^N
+	case OpTxorN: // T^N
 		return T ^ N
-	case opNotT: // ~T
+	case OpNotT: // ~T
 		return ^T
-	case opNeqT: // N==T
-		return boolValue[N == T]
-	case opNleT: // N<T
-		return boolValue[int16(N) < int16(T)]
-	case opNrshiftT: // N>>T
+	case OpNeqT: // N==T
+		return boolValue(N == T)
+	case OpNleT: // N<T
+		return boolValue(int16(N) < int16(T))
+	case OpNrshiftT: // N>>T
 		return N >> (T & 0xf)
-	case opTminus1: // T-1
+	case OpTminus1: // T-1
 		return T - 1
-	case opR: // R (rT)
+	case OpR: // R (rT)
 		return R
-	case opAtT: // [T]
+	case OpAtT: // [T]
 		return c.readAt(T)
-	case opNlshiftT: // N<<T
+	case OpNlshiftT: // N<<T
 		return N << (T & 0xf)
-	case opDepth: // depth (dsp)
+	case OpDepth: // depth (dsp)
 		return (c.r.depth() << 8) | c.d.depth()
-	case opNuleT: // Nu<T
-		return boolValue[N < T]
-	default:
-		panic("invalid instruction")
+	case OpNuleT: // Nu<T
+		return boolValue(N < T)
+	case OpIoAtT: // io[T]
+		return c.readIO(T)
+	default: // rejected by alu
+		return 0
 	}
 }
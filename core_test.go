@@ -1,8 +1,12 @@
 package j1
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"testing"
+	"time"
 )
 
 func cmp(t *testing.T, got, want Core) {
@@ -64,55 +68,55 @@ func TestEval(t *testing.T) {
 			end: Core{pc: 2, st0: 0xfe, d: stack{data: [32]uint16{0x00, 0x00, 0xff}, sp: 2}},
 		},
 		{ // dup
-			ins: []Instruction{Literal(0xff), ALU{Opcode: opT, TtoN: true, Ddir: 1}},
+			ins: []Instruction{Literal(0xff), ALU{Opcode: OpT, TtoN: true, Ddir: 1}},
 			end: Core{pc: 2, st0: 0xff, d: stack{data: [32]uint16{0x00, 0x00, 0xff}, sp: 2}},
 		},
 		{ // over
-			ins: []Instruction{Literal(0xaa), Literal(0xbb), ALU{Opcode: opN, TtoN: true, Ddir: 1}},
+			ins: []Instruction{Literal(0xaa), Literal(0xbb), ALU{Opcode: OpN, TtoN: true, Ddir: 1}},
 			end: Core{pc: 3, st0: 0xaa, d: stack{data: [32]uint16{0x00, 0x00, 0xaa, 0xbb}, sp: 3}},
 		},
 		{ // invert
-			ins: []Instruction{Literal(0x00ff), ALU{Opcode: opNotT}},
+			ins: []Instruction{Literal(0x00ff), ALU{Opcode: OpNotT}},
 			end: Core{pc: 2, st0: 0xff00, d: stack{sp: 1}},
 		},
 		{ // +
-			ins: []Instruction{Literal(1), Literal(2), ALU{Opcode: opTplusN, Ddir: -1}},
+			ins: []Instruction{Literal(1), Literal(2), ALU{Opcode: OpTplusN, Ddir: -1}},
 			end: Core{pc: 3, st0: 3, d: stack{data: [32]uint16{0, 0, 1}, sp: 1}},
 		},
 		{ // swap
-			ins: []Instruction{Literal(2), Literal(3), ALU{Opcode: opN, TtoN: true}},
+			ins: []Instruction{Literal(2), Literal(3), ALU{Opcode: OpN, TtoN: true}},
 			end: Core{pc: 3, st0: 2, d: stack{data: [32]uint16{0, 0, 3}, sp: 2}},
 		},
 		{ // nip
-			ins: []Instruction{Literal(2), Literal(3), ALU{Opcode: opT, Ddir: -1}},
+			ins: []Instruction{Literal(2), Literal(3), ALU{Opcode: OpT, Ddir: -1}},
 			end: Core{pc: 3, st0: 3, d: stack{data: [32]uint16{0, 0, 2}, sp: 1}},
 		},
 		{ // drop
-			ins: []Instruction{Literal(2), Literal(3), ALU{Opcode: opN, Ddir: -1}},
+			ins: []Instruction{Literal(2), Literal(3), ALU{Opcode: OpN, Ddir: -1}},
 			end: Core{pc: 3, st0: 2, d: stack{data: [32]uint16{0, 0, 2}, sp: 1}},
 		},
 		{ // ;
-			ins: []Instruction{Call(10), Call(20), ALU{Opcode: opT, RtoPC: true, Rdir: -1}},
+			ins: []Instruction{Call(10), Call(20), ALU{Opcode: OpT, RtoPC: true, Rdir: -1}},
 			end: Core{pc: 11, r: stack{data: [32]uint16{0, 2, 22}, sp: 1}},
 		},
 		{ // >r
-			ins: []Instruction{Literal(10), ALU{Opcode: opN, TtoR: true, Ddir: -1, Rdir: 1}},
+			ins: []Instruction{Literal(10), ALU{Opcode: OpN, TtoR: true, Ddir: -1, Rdir: 1}},
 			end: Core{pc: 2, r: stack{data: [32]uint16{0, 10}, sp: 1}},
 		},
 		{ // r>
-			ins: []Instruction{Literal(10), Call(20), ALU{Opcode: opR, TtoN: true, TtoR: true, Ddir: 1, Rdir: -1}},
+			ins: []Instruction{Literal(10), Call(20), ALU{Opcode: OpR, TtoN: true, TtoR: true, Ddir: 1, Rdir: -1}},
 			end: Core{pc: 21, st0: 4, d: stack{data: [32]uint16{0, 0, 10}, sp: 2}, r: stack{data: [32]uint16{10, 4}}},
 		},
 		{ // r@
-			ins: []Instruction{Literal(10), ALU{Opcode: opR, TtoN: true, TtoR: true, Ddir: 1}},
+			ins: []Instruction{Literal(10), ALU{Opcode: OpR, TtoN: true, TtoR: true, Ddir: 1}},
 			end: Core{pc: 2, d: stack{data: [32]uint16{0, 0, 10}, sp: 2}, r: stack{data: [32]uint16{10}}},
 		},
 		{ // @
-			ins: []Instruction{ALU{Opcode: opAtT}},
+			ins: []Instruction{ALU{Opcode: OpAtT}},
 			end: Core{pc: 1},
 		},
 		{ // !
-			ins: []Instruction{Literal(1), Literal(0), ALU{Opcode: opN, NtoAtT: true, Ddir: -1}},
+			ins: []Instruction{Literal(1), Literal(0), ALU{Opcode: OpN, NtoAtT: true, Ddir: -1}},
 			end: Core{pc: 3, st0: 1, d: stack{data: [32]uint16{0, 0, 1}, sp: 1}, memory: [8192]uint16{1}},
 		},
 	}
@@ -134,25 +138,25 @@ func TestNextST0(t *testing.T) {
 		st0   uint16
 		state Core
 	}{
-		{ins: ALU{Opcode: opT}, st0: 0xff, state: Core{st0: 0xff}},
-		{ins: ALU{Opcode: opN}, st0: 0xbb, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
-		{ins: ALU{Opcode: opTplusN}, st0: 0x01ba, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
-		{ins: ALU{Opcode: opTandN}, st0: 0xbb, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
-		{ins: ALU{Opcode: opTorN}, st0: 0xff, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
-		{ins: ALU{Opcode: opTxorN}, st0: 0x44, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
-		{ins: ALU{Opcode: opNotT}, st0: 0xff55, state: Core{st0: 0xaa}},
-		{ins: ALU{Opcode: opNeqT}, st0: 0x00, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
-		{ins: ALU{Opcode: opNeqT}, st0: 0xffff, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xff}, sp: 2}}},
-		{ins: ALU{Opcode: opNleT}, st0: 0xffff, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
-		{ins: ALU{Opcode: opNleT}, st0: 0x00, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xff}, sp: 2}}},
-		{ins: ALU{Opcode: opNrshiftT}, st0: 0x3f, state: Core{st0: 0x02, d: stack{data: [32]uint16{0, 0xaa, 0xff}, sp: 2}}},
-		{ins: ALU{Opcode: opTminus1}, st0: 0x54, state: Core{st0: 0x55}},
-		{ins: ALU{Opcode: opR}, st0: 0x5, state: Core{r: stack{data: [32]uint16{0, 0x05}, sp: 1}}},
-		{ins: ALU{Opcode: opAtT}, st0: 0x5, state: Core{st0: 0x02, memory: [8192]uint16{0, 5, 10}}},
-		{ins: ALU{Opcode: opNlshiftT}, st0: 0x3fc, state: Core{st0: 0x02, d: stack{data: [32]uint16{0, 0xaa, 0xff}, sp: 2}}},
-		{ins: ALU{Opcode: opDepth}, st0: 0x305, state: Core{r: stack{sp: 3}, d: stack{sp: 5}}},
-		{ins: ALU{Opcode: opNuleT}, st0: 0xffff, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
-		{ins: ALU{Opcode: opNuleT}, st0: 0x00, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xff}, sp: 2}}},
+		{ins: ALU{Opcode: OpT}, st0: 0xff, state: Core{st0: 0xff}},
+		{ins: ALU{Opcode: OpN}, st0: 0xbb, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
+		{ins: ALU{Opcode: OpTplusN}, st0: 0x01ba, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
+		{ins: ALU{Opcode: OpTandN}, st0: 0xbb, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
+		{ins: ALU{Opcode: OpTorN}, st0: 0xff, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
+		{ins: ALU{Opcode: OpTxorN}, st0: 0x44, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
+		{ins: ALU{Opcode: OpNotT}, st0: 0xff55, state: Core{st0: 0xaa}},
+		{ins: ALU{Opcode: OpNeqT}, st0: 0x00, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
+		{ins: ALU{Opcode: OpNeqT}, st0: 0xffff, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xff}, sp: 2}}},
+		{ins: ALU{Opcode: OpNleT}, st0: 0xffff, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
+		{ins: ALU{Opcode: OpNleT}, st0: 0x00, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xff}, sp: 2}}},
+		{ins: ALU{Opcode: OpNrshiftT}, st0: 0x3f, state: Core{st0: 0x02, d: stack{data: [32]uint16{0, 0xaa, 0xff}, sp: 2}}},
+		{ins: ALU{Opcode: OpTminus1}, st0: 0x54, state: Core{st0: 0x55}},
+		{ins: ALU{Opcode: OpR}, st0: 0x5, state: Core{r: stack{data: [32]uint16{0, 0x05}, sp: 1}}},
+		{ins: ALU{Opcode: OpAtT}, st0: 0x5, state: Core{st0: 0x02, memory: [8192]uint16{0, 5, 10}}},
+		{ins: ALU{Opcode: OpNlshiftT}, st0: 0x3fc, state: Core{st0: 0x02, d: stack{data: [32]uint16{0, 0xaa, 0xff}, sp: 2}}},
+		{ins: ALU{Opcode: OpDepth}, st0: 0x305, state: Core{r: stack{sp: 3}, d: stack{sp: 5}}},
+		{ins: ALU{Opcode: OpNuleT}, st0: 0xffff, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xbb}, sp: 2}}},
+		{ins: ALU{Opcode: OpNuleT}, st0: 0x00, state: Core{st0: 0xff, d: stack{data: [32]uint16{0, 0xaa, 0xff}, sp: 2}}},
 	}
 	for _, tc := range testCases {
 		t.Run(fmt.Sprint(tc.ins), func(t *testing.T) {
@@ -177,6 +181,51 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+func TestWriteErrors(t *testing.T) {
+	testCases := []struct {
+		size int
+		addr uint16
+		err  error
+	}{
+		{size: 3, addr: 2, err: ErrOddLength},
+		{size: 0x4002, addr: 0x4000, err: ErrImageTooLarge},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			j1 := New(&mocConsole{})
+			_, err := j1.Write(make([]byte, tc.size))
+			var f *Fault
+			if !errors.Is(err, tc.err) || !errors.As(err, &f) || f.Addr != tc.addr {
+				t.Errorf("got %v, want %0.4X: %v", err, tc.addr, tc.err)
+			}
+		})
+	}
+}
+
+func TestFault(t *testing.T) {
+	j1 := New(&mocConsole{})
+	j1.Execute(Literal(1))
+	j1.Execute(ALU{Opcode: nOps + 3, Ddir: -1})
+	var f *Fault
+	if err := j1.Err(); !errors.Is(err, ErrInvalidInstruction) || !errors.As(err, &f) || f.Addr != 2 {
+		t.Fatalf("got %v, want 0002: %v", err, ErrInvalidInstruction)
+	}
+	if j1.pc != 1 || j1.cycles != 1 || j1.st0 != 1 || j1.d.sp != 1 {
+		t.Errorf("state changed: pc %v cycles %v st0 %v", j1.pc, j1.cycles, j1.st0)
+	}
+	j1.Execute(Literal(2))
+	if j1.st0 != 1 {
+		t.Errorf("executed after fault")
+	}
+	if err := j1.Run(context.Background()); err != j1.Err() {
+		t.Errorf("Run: got %v, want %v", err, j1.Err())
+	}
+	j1.Reset()
+	if err := j1.Err(); err != nil {
+		t.Errorf("after Reset: %v", err)
+	}
+}
+
 func TestReset(t *testing.T) {
 	j1 := &Core{pc: 100, d: stack{sp: 2}, r: stack{sp: 3}, st0: 5}
 	j1.Reset()
@@ -184,3 +233,80 @@ func TestReset(t *testing.T) {
 		t.Errorf("got %v", j1)
 	}
 }
+
+type mocDevice map[uint16]uint16
+
+func (m mocDevice) Read(addr uint16) uint16  { return m[addr] }
+func (m mocDevice) Write(addr, value uint16) { m[addr] = value }
+
+func TestAttach(t *testing.T) {
+	dev := mocDevice{}
+	j1 := New(&mocConsole{})
+	j1.Attach(0x7010, 4, dev)
+	j1.writeAt(0x7012, 5)
+	j1.writeAt(0x7014, 6) // out of range
+	if v, ok := dev[2]; !ok || v != 5 {
+		t.Errorf("got %v, want 5", v)
+	}
+	if len(dev) != 1 {
+		t.Errorf("got %v, want 1 entry", dev)
+	}
+	if v := j1.readAt(0x7012); v != 5 {
+		t.Errorf("got %v, want 5", v)
+	}
+	if v := j1.readAt(0x700e); v != 0 {
+		t.Errorf("got %v, want 0", v)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	j1 := New(&mocConsole{})
+	for _, ins := range []Instruction{Literal(1), Literal(2), ALU{Opcode: OpTplusN, Ddir: -1}} {
+		j1.Execute(ins)
+	}
+	if v := j1.Cycles(); v != 3 {
+		t.Errorf("got %v, want 3", v)
+	}
+}
+
+type stopConsole struct {
+	mocConsole
+	cancel func()
+}
+
+func (s *stopConsole) Stop() { s.cancel() }
+
+func TestBoot(t *testing.T) {
+	image, err := os.ReadFile("testdata/j1e.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	j1 := New(&stopConsole{cancel: cancel})
+	if _, err := j1.Write(image); err != nil {
+		t.Fatal(err)
+	}
+	if err := j1.Boot([]byte("replaced")); err != nil {
+		t.Fatal(err)
+	}
+	if err := j1.Boot([]byte(": sq dup * ;\n7 sq 3000 ! bye")); err != nil {
+		t.Fatal(err)
+	}
+	if v := j1.readAt(0x4000); v != 28 {
+		t.Errorf("size: got %v, want 28", v)
+	}
+	if v := j1.readAt(0x4002); v != 0x203a {
+		t.Errorf("content: got %0.4X, want 203A", v)
+	}
+	j1.Run(ctx)
+	if ctx.Err() == context.DeadlineExceeded {
+		t.Fatal("bye not reached")
+	}
+	if v := j1.memory[0x3000>>1]; v != 49 {
+		t.Errorf("got %v, want 49", v)
+	}
+	if err := j1.Boot(make([]byte, maxBoot+1)); err == nil {
+		t.Error("want error")
+	}
+}
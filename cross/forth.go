@@ -0,0 +1,827 @@
+// Package cross compiles Forth for J1 targets on a host Forth written in Go
+package cross
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Forth is minimal host Forth, sufficient to run metacompilers written for
+// gforth. Cells are 64 bit, names are case insensitive.
+type Forth struct {
+	mem    []byte
+	dp     int
+	stack  []int64
+	rstack []int64
+	fstack []float64
+
+	words     []*word
+	wordlists []map[string]*word
+	order     []int
+	current   int
+	forth     int
+
+	def    *word // colon definition in progress
+	defWid int   // wordlist it goes to
+	last   *word // most recent definition
+	ctl    []int64
+	// forward branches of leave, per do loop
+	leaves [][]int64
+
+	src   []*source
+	files []*os.File
+
+	// Dir is base directory of included files, other files are relative to
+	// working directory
+	Dir string
+	// Out is where output goes
+	Out io.Writer
+	// Provided files are skipped by include, their words are built in
+	Provided map[string]bool
+}
+
+// ErrBye is returned once bye is executed
+var ErrBye = errors.New("bye")
+
+// Error of Forth execution
+type Error struct {
+	Code int64
+	Msg  string
+	File string
+	Line int
+}
+
+func (e *Error) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = fmt.Sprintf("throw %d", e.Code)
+	}
+	if e.File != "" {
+		return fmt.Sprintf("%v:%d: %v", e.File, e.Line, msg)
+	}
+	return msg
+}
+
+type op uint8
+
+const (
+	opCall op = iota
+	opLit
+	opBranch
+	op0Branch
+	opExit
+	opDo
+	opQDo
+	opLoop
+	opPlusLoop
+	opLeave
+	opDoes
+	opCompile
+	opFLit
+	opNext
+)
+
+type inst struct {
+	op op
+	w  *word
+	n  int64
+}
+
+type word struct {
+	name      string
+	xt        int64
+	immediate bool
+	prim      func(*Forth)
+	code      []inst
+	colon     bool
+	pfa       int64
+	does      []inst
+	doesAt    int
+}
+
+// source of input, lines are copied to own buffer in data space
+type source struct {
+	name  string // as given to include
+	dir   string // of file, relative names are resolved against it
+	line  int
+	r     *bufio.Reader
+	buf   int64 // address
+	size  int64
+	eval  bool
+	named int64 // address of name in data space, once placed
+}
+
+// memory layout
+const (
+	memSize   = 1 << 22
+	addrState = 0x00
+	addrBase  = 0x08
+	addrIn    = 0x10
+	addrWarn  = 0x18
+	addrWord  = 0x100  // word buffer
+	addrHold  = 0x300  // end of pictured output buffer
+	addrPad   = 0x400  // pad
+	addrTib   = 0x1000 // input buffers, one per nesting level
+	tibSize   = 0x1000
+	maxDepth  = 32
+	addrDict  = addrTib + tibSize*maxDepth
+	cellSize  = 8
+)
+
+// NewForth host system
+func NewForth() *Forth {
+	f := &Forth{
+		mem: make([]byte, memSize),
+		dp:  addrDict,
+		Out: os.Stdout,
+	}
+	f.forth = f.Wordlist()
+	f.order = []int{f.forth}
+	f.current = f.forth
+	f.store(addrBase, 10)
+	f.store(addrWarn, -1)
+	f.primitives()
+	return f
+}
+
+func (f *Forth) throw(code int64, msg string, args ...interface{}) {
+	panic(&Error{Code: code, Msg: fmt.Sprintf(msg, args...)})
+}
+
+// Push value on data stack
+func (f *Forth) Push(v int64) { f.stack = append(f.stack, v) }
+
+// Pop value from data stack
+func (f *Forth) Pop() int64 {
+	n := len(f.stack)
+	if n == 0 {
+		f.throw(-4, "stack underflow")
+	}
+	v := f.stack[n-1]
+	f.stack = f.stack[:n-1]
+	return v
+}
+
+// Depth of data stack
+func (f *Forth) Depth() int { return len(f.stack) }
+
+func (f *Forth) peek(i int) int64 {
+	n := len(f.stack)
+	if i >= n {
+		f.throw(-4, "stack underflow")
+	}
+	return f.stack[n-1-i]
+}
+
+func (f *Forth) rpush(v int64) { f.rstack = append(f.rstack, v) }
+
+func (f *Forth) rpop() int64 {
+	n := len(f.rstack)
+	if n == 0 {
+		f.throw(-6, "return stack underflow")
+	}
+	v := f.rstack[n-1]
+	f.rstack = f.rstack[:n-1]
+	return v
+}
+
+func (f *Forth) addr(a int64, n int) int {
+	if a < 0 || a+int64(n) > int64(len(f.mem)) {
+		f.throw(-9, "invalid memory address %#x", a)
+	}
+	return int(a)
+}
+
+func (f *Forth) fetch(a int64) int64 {
+	i := f.addr(a, cellSize)
+	return int64(binary.LittleEndian.Uint64(f.mem[i:]))
+}
+
+func (f *Forth) store(a, v int64) {
+	i := f.addr(a, cellSize)
+	binary.LittleEndian.PutUint64(f.mem[i:], uint64(v))
+}
+
+func (f *Forth) cfetch(a int64) int64 { return int64(f.mem[f.addr(a, 1)]) }
+
+func (f *Forth) cstore(a, v int64) { f.mem[f.addr(a, 1)] = byte(v) }
+
+// bytes of string at addr
+func (f *Forth) bytes(a, n int64) []byte {
+	if n < 0 {
+		f.throw(-24, "invalid length")
+	}
+	i := f.addr(a, int(n))
+	return f.mem[i : i+int(n)]
+}
+
+func (f *Forth) str(a, n int64) string { return string(f.bytes(a, n)) }
+
+// here is data space pointer
+func (f *Forth) here() int64 { return int64(f.dp) }
+
+func (f *Forth) allot(n int64) {
+	f.addr(int64(f.dp)+n, 0)
+	f.dp += int(n)
+}
+
+func (f *Forth) comma(v int64) {
+	f.store(f.here(), v)
+	f.allot(cellSize)
+}
+
+// place string in data space, returns its address
+func (f *Forth) place(s string) int64 {
+	a := f.here()
+	f.allot(int64(len(s)))
+	copy(f.mem[a:], s)
+	return a
+}
+
+// Wordlist creates new empty wordlist and returns its id
+func (f *Forth) Wordlist() int {
+	f.wordlists = append(f.wordlists, make(map[string]*word))
+	return len(f.wordlists) - 1
+}
+
+func (f *Forth) wid(v int64) int {
+	if v < 0 || v >= int64(len(f.wordlists)) {
+		f.throw(-13, "invalid wordlist %d", v)
+	}
+	return int(v)
+}
+
+func (f *Forth) newWord(name string) *word {
+	w := &word{name: name, xt: int64(len(f.words))}
+	f.words = append(f.words, w)
+	return w
+}
+
+func (f *Forth) reveal(w *word, wid int) {
+	f.wordlists[wid][strings.ToLower(w.name)] = w
+	f.last = w
+}
+
+// Define primitive in current wordlist
+func (f *Forth) Define(name string, fn func(*Forth)) {
+	w := f.newWord(name)
+	w.prim = fn
+	f.reveal(w, f.current)
+}
+
+// Immediate marks most recent definition immediate
+func (f *Forth) Immediate() {
+	if f.last == nil {
+		f.throw(-32, "nothing to mark immediate")
+	}
+	f.last.immediate = true
+}
+
+// Current wordlist for new definitions
+func (f *Forth) Current() int { return f.current }
+
+// SetCurrent wordlist for new definitions
+func (f *Forth) SetCurrent(wid int) { f.current = wid }
+
+// Order of wordlists, first one is searched first
+func (f *Forth) Order() []int { return append([]int(nil), f.order...) }
+
+// SetOrder of wordlists, first one is searched first
+func (f *Forth) SetOrder(wids ...int) { f.order = append([]int(nil), wids...) }
+
+// ForthWordlist is wordlist of host words
+func (f *Forth) ForthWordlist() int { return f.forth }
+
+func (f *Forth) search(name string, wid int) *word {
+	return f.wordlists[wid][strings.ToLower(name)]
+}
+
+func (f *Forth) find(name string) *word {
+	for _, wid := range f.order {
+		if w := f.search(name, wid); w != nil {
+			return w
+		}
+	}
+	return nil
+}
+
+func (f *Forth) tick(name string) *word {
+	w := f.find(name)
+	if w == nil {
+		f.throw(-13, "%v?", name)
+	}
+	return w
+}
+
+func (f *Forth) xt(v int64) *word {
+	if v < 0 || v >= int64(len(f.words)) {
+		f.throw(-13, "invalid execution token %d", v)
+	}
+	return f.words[v]
+}
+
+func (f *Forth) compiling() bool { return f.fetch(addrState) != 0 }
+
+func (f *Forth) compile(in inst) {
+	if f.def == nil {
+		f.throw(-14, "interpreting a compile-only word")
+	}
+	f.def.code = append(f.def.code, in)
+}
+
+func (f *Forth) mark() int64 {
+	if f.def == nil {
+		f.throw(-14, "interpreting a compile-only word")
+	}
+	return int64(len(f.def.code))
+}
+
+func (f *Forth) literal(n int64) { f.compile(inst{op: opLit, n: n}) }
+
+// Execute word by name
+func (f *Forth) Execute(name string) (err error) {
+	defer f.recover(&err)
+	f.execute(f.tick(name))
+	return nil
+}
+
+func (f *Forth) execute(w *word) {
+	switch {
+	case w.prim != nil:
+		w.prim(f)
+	case w.colon:
+		f.run(w.code, 0)
+	default:
+		f.Push(w.pfa)
+		if w.does != nil {
+			f.run(w.does, w.doesAt)
+		}
+	}
+}
+
+func (f *Forth) run(code []inst, ip int) {
+	rbase := len(f.rstack)
+	for ip < len(code) {
+		in := code[ip]
+		ip++
+		switch in.op {
+		case opCall:
+			f.execute(in.w)
+		case opLit:
+			f.Push(in.n)
+		case opBranch:
+			ip = int(in.n)
+		case op0Branch:
+			if f.Pop() == 0 {
+				ip = int(in.n)
+			}
+		case opExit:
+			return
+		case opDo, opQDo:
+			index, limit := f.Pop(), f.Pop()
+			if in.op == opQDo && index == limit {
+				ip = int(in.n)
+				break
+			}
+			f.rpush(limit)
+			f.rpush(index)
+		case opLoop, opPlusLoop:
+			step := int64(1)
+			if in.op == opPlusLoop {
+				step = f.Pop()
+			}
+			if len(f.rstack) < rbase+2 {
+				f.throw(-6, "loop without do")
+			}
+			n := len(f.rstack)
+			limit, index := f.rstack[n-2], f.rstack[n-1]
+			// loop ends when index crosses boundary of limit-1 and limit
+			before := index - limit
+			after := before + step
+			if (before^after)&(before^step) < 0 {
+				f.rstack = f.rstack[:n-2]
+				break
+			}
+			f.rstack[n-1] = index + step
+			ip = int(in.n)
+		case opNext:
+			if n := len(f.rstack) - 1; n >= rbase && f.rstack[n] != 0 {
+				f.rstack[n]--
+				ip = int(in.n)
+				break
+			}
+			f.rpop()
+		case opLeave:
+			f.rpop()
+			f.rpop()
+			ip = int(in.n)
+		case opDoes:
+			if f.last == nil {
+				f.throw(-31, "does> without create")
+			}
+			f.last.does, f.last.doesAt = code, ip
+			return
+		case opCompile:
+			f.compile(inst{op: opCall, w: in.w})
+		case opFLit:
+			f.fpush(math.Float64frombits(uint64(in.n)))
+		}
+	}
+}
+
+func (f *Forth) recover(err *error) {
+	if r := recover(); r != nil {
+		if r == ErrBye {
+			*err = ErrBye
+			f.reset()
+			return
+		}
+		e, ok := r.(*Error)
+		if !ok {
+			panic(r)
+		}
+		if e.File == "" && len(f.src) > 0 {
+			s := f.src[len(f.src)-1]
+			e.File, e.Line = s.name, s.line
+		}
+		*err = e
+		f.reset()
+	}
+}
+
+// reset state after error
+func (f *Forth) reset() {
+	f.stack, f.rstack, f.fstack, f.ctl, f.leaves = nil, nil, nil, nil, nil
+	f.def = nil
+	f.store(addrState, 0)
+	f.src = nil
+}
+
+// input handling
+
+func (f *Forth) input() *source {
+	if len(f.src) == 0 {
+		f.throw(-37, "no input")
+	}
+	return f.src[len(f.src)-1]
+}
+
+func (f *Forth) in() int64     { return f.fetch(addrIn) }
+func (f *Forth) setIn(v int64) { f.store(addrIn, v) }
+
+func (f *Forth) pushSource(s *source) {
+	if len(f.src) >= maxDepth {
+		f.throw(-52, "nesting too deep")
+	}
+	if len(f.src) > 0 {
+		f.rpush(f.in())
+	}
+	if s.r != nil {
+		s.buf = int64(addrTib + tibSize*len(f.src))
+	}
+	f.src = append(f.src, s)
+	f.setIn(0)
+}
+
+func (f *Forth) popSource() {
+	f.src = f.src[:len(f.src)-1]
+	if len(f.src) > 0 {
+		f.setIn(f.rpop())
+	}
+}
+
+// refill reads next line of file input
+func (f *Forth) refill() bool {
+	s := f.input()
+	if s.r == nil {
+		return false
+	}
+	line, err := s.r.ReadString('\n')
+	if err != nil && line == "" {
+		return false
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) > tibSize {
+		f.throw(-18, "line too long")
+	}
+	copy(f.mem[s.buf:], line)
+	s.size = int64(len(line))
+	s.line++
+	f.setIn(0)
+	return true
+}
+
+// sourceFile is innermost source file, as it was named
+func (f *Forth) sourceFile() *source {
+	for i := len(f.src) - 1; i >= 0; i-- {
+		if s := f.src[i]; !s.eval && s.name != "evaluate" {
+			return s
+		}
+	}
+	return f.input()
+}
+
+func (f *Forth) source() (int64, int64) {
+	s := f.input()
+	return s.buf, s.size
+}
+
+// parse up to delimiter, skipping leading ones if skip is set. Space
+// delimiter matches any white space.
+func (f *Forth) parse(delim byte, skip bool) (int64, int64) {
+	buf, size := f.source()
+	in := f.in()
+	match := func(c byte) bool {
+		if delim == ' ' {
+			return c <= ' '
+		}
+		return c == delim
+	}
+	if skip {
+		for in < size && match(f.mem[buf+in]) {
+			in++
+		}
+	}
+	start := in
+	for in < size && !match(f.mem[buf+in]) {
+		in++
+	}
+	end := in
+	if in < size {
+		in++
+	}
+	f.setIn(in)
+	return buf + start, end - start
+}
+
+// comment skips up to closing parenthesis, which may be on a later line
+func (f *Forth) comment() {
+	for {
+		buf, size := f.source()
+		for in := f.in(); in < size; in++ {
+			if f.mem[buf+in] == ')' {
+				f.setIn(in + 1)
+				return
+			}
+		}
+		f.setIn(size)
+		if !f.refill() {
+			return
+		}
+	}
+}
+
+// Word parses next space delimited name
+func (f *Forth) word() string {
+	a, n := f.parse(' ', true)
+	return f.str(a, n)
+}
+
+// name parses next name, which must be present
+func (f *Forth) name() string {
+	s := f.word()
+	if s == "" {
+		f.throw(-16, "attempt to use zero-length string as a name")
+	}
+	return s
+}
+
+// number converts s in current base, reports success
+func (f *Forth) number(s string) (int64, bool, bool) {
+	base := f.fetch(addrBase)
+	if len(s) == 3 && s[0] == '\'' && s[2] == '\'' {
+		return int64(s[1]), false, true
+	}
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "$"):
+		base, s = 16, s[1:]
+	case strings.HasPrefix(s, "#"):
+		base, s = 10, s[1:]
+	case strings.HasPrefix(s, "%"):
+		base, s = 2, s[1:]
+	}
+	if strings.HasPrefix(s, "-") {
+		neg, s = true, s[1:]
+	}
+	double := false
+	if strings.HasSuffix(s, ".") && len(s) > 1 {
+		double, s = true, s[:len(s)-1]
+	}
+	if s == "" {
+		return 0, false, false
+	}
+	v, err := strconv.ParseUint(s, int(base), 64)
+	if err != nil {
+		return 0, false, false
+	}
+	n := int64(v)
+	if neg {
+		n = -n
+	}
+	return n, double, true
+}
+
+func (f *Forth) interpret() {
+	for {
+		name := f.word()
+		if name == "" {
+			if !f.refill() {
+				return
+			}
+			continue
+		}
+		f.interpretWord(name)
+	}
+}
+
+func (f *Forth) interpretWord(name string) {
+	if w := f.find(name); w != nil {
+		if f.compiling() && !w.immediate {
+			f.compile(inst{op: opCall, w: w})
+		} else {
+			f.execute(w)
+		}
+		return
+	}
+	n, double, ok := f.number(name)
+	if !ok {
+		r, ok := f.float(name)
+		if !ok {
+			f.throw(-13, "%v?", name)
+		}
+		if f.compiling() {
+			f.compile(inst{op: opFLit, n: int64(math.Float64bits(r))})
+		} else {
+			f.fpush(r)
+		}
+		return
+	}
+	switch {
+	case f.compiling():
+		f.literal(n)
+		if double {
+			f.literal(n >> 63)
+		}
+	case double:
+		f.Push(n)
+		f.Push(n >> 63)
+	default:
+		f.Push(n)
+	}
+}
+
+// float converts s to floating point number, as in decimal base only
+func (f *Forth) float(s string) (float64, bool) {
+	if f.fetch(addrBase) != 10 || !strings.ContainsAny(s, "eE") {
+		return 0, false
+	}
+	// 1e is valid Forth, 1e0 in Go
+	if strings.HasSuffix(s, "e") || strings.HasSuffix(s, "E") {
+		s += "0"
+	}
+	r, err := strconv.ParseFloat(s, 64)
+	return r, err == nil
+}
+
+func (f *Forth) fpush(r float64) { f.fstack = append(f.fstack, r) }
+
+func (f *Forth) fpop() float64 {
+	n := len(f.fstack)
+	if n == 0 {
+		f.throw(-45, "floating-point stack underflow")
+	}
+	r := f.fstack[n-1]
+	f.fstack = f.fstack[:n-1]
+	return r
+}
+
+// Evaluate Forth source text
+func (f *Forth) Evaluate(text string) (err error) {
+	defer f.recover(&err)
+	f.include("evaluate", "", strings.NewReader(text))
+	return nil
+}
+
+// Include Forth source file, relative to Dir
+func (f *Forth) Include(name string) (err error) {
+	defer f.recover(&err)
+	f.included(name)
+	return nil
+}
+
+// Interpret Forth source read from r
+func (f *Forth) Interpret(name string, r io.Reader) (err error) {
+	defer f.recover(&err)
+	f.include(name, "", r)
+	return nil
+}
+
+func (f *Forth) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	dir := f.Dir
+	for i := len(f.src) - 1; i >= 0; i-- {
+		if s := f.src[i]; s.dir != "" {
+			dir = s.dir
+			break
+		}
+	}
+	return filepath.Join(dir, name)
+}
+
+func (f *Forth) included(name string) {
+	if f.Provided[filepath.Base(name)] {
+		return
+	}
+	fd, err := os.Open(f.path(name))
+	if err != nil {
+		f.throw(-38, "%v", err)
+	}
+	defer fd.Close()
+	f.include(name, filepath.Dir(fd.Name()), fd)
+}
+
+func (f *Forth) include(name, dir string, r io.Reader) {
+	f.pushSource(&source{name: name, dir: dir, r: bufio.NewReader(r)})
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(*Error); ok && e.File == "" {
+				e.File, e.Line = name, f.input().line
+			}
+			panic(r)
+		}
+	}()
+	f.interpret()
+	f.popSource()
+}
+
+// evaluate string in data space, as EVALUATE does
+func (f *Forth) evaluate(a, n int64) {
+	f.pushSource(&source{name: "evaluate", buf: a, size: n, eval: true})
+	f.interpret()
+	f.popSource()
+}
+
+// skip conditionally compiled text up to matching [else] or [then]
+func (f *Forth) skip(elseOK bool) {
+	depth := 0
+	for {
+		name := strings.ToLower(f.word())
+		switch name {
+		case "":
+			if !f.refill() {
+				f.throw(-39, "unexpected end of file in [if]")
+			}
+		case "[if]":
+			depth++
+		case "[else]":
+			if depth == 0 && elseOK {
+				return
+			}
+		case "[then]":
+			if depth == 0 {
+				return
+			}
+			depth--
+		case "\\":
+			_, size := f.source()
+			f.setIn(size)
+		case "(":
+			f.comment()
+		}
+	}
+}
+
+// Stack returns copy of data stack, top last
+func (f *Forth) Stack() []int64 { return append([]int64(nil), f.stack...) }
+
+func (f *Forth) write(b []byte) {
+	if _, err := f.Out.Write(b); err != nil {
+		f.throw(-57, "%v", err)
+	}
+}
+
+func (f *Forth) file(v int64) *os.File {
+	if v <= 0 || v > int64(len(f.files)) || f.files[v-1] == nil {
+		f.throw(-37, "invalid file id %d", v)
+	}
+	return f.files[v-1]
+}
+
+// ior for Go error
+func ior(err error) int64 {
+	if err != nil {
+		return -37
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package cross
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestForth(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want []int64
+		out  string
+	}{
+		{in: "1 2 + 3 *", want: []int64{9}},
+		{in: "hex ff decimal 10", want: []int64{255, 10}},
+		{in: "-7 2 /mod", want: []int64{1, -4}},
+		{in: ": sq dup * ; 5 sq", want: []int64{25}},
+		{in: ": f 0 swap 0 ?do i + loop ; 4 f 0 f", want: []int64{6, 0}},
+		{in: ": f 0 10 0 do i + 2 +loop ; f", want: []int64{20}},
+		{in: ": f begin dup while 1- repeat ; 3 f", want: []int64{0}},
+		{in: ": f if 1 else 2 then ; 0 f -1 f", want: []int64{2, 1}},
+		{in: ": f 10 0 do i 3 = if i leave then loop ; f", want: []int64{3}},
+		{in: ": k create , does> @ 1+ ; 5 k six six", want: []int64{6}},
+		{in: "variable v 7 v ! v @ 3 constant c c", want: []int64{7, 3}},
+		{in: "wordlist dup >r set-current : w 1 ; forth-wordlist set-current get-order r> swap 1+ set-order w", want: []int64{1}},
+		{in: "s\" abc\" nip char x bl", want: []int64{3, 'x', ' '}},
+		{in: ": f [ 2 3 * ] literal ; f", want: []int64{6}},
+		{in: "1 [if] 2 [else] 3 [then] 0 [if] 4 [else] 5 [then]", want: []int64{2, 5}},
+		{in: ": f .\" hi\" 42 . ; f 0 0 <# # # #> type", out: "hi42 00"},
+		{in: ": f ( n -- ) recurse ; \\ comment\n' f >body drop", want: nil},
+		{in: ": f 0 swap for 1+ next ; 3 f", want: []int64{4}},
+		{in: "( spans\nlines ) 5", want: []int64{5}},
+		{in: "wordlist dup set-current : w 2 ; forth-wordlist set-current get-order rot swap 1+ set-order w", want: []int64{2}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			f := NewForth()
+			var out bytes.Buffer
+			f.Out = &out
+			if err := f.Evaluate(tc.in); err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Stack(); fmt.Sprint(got) != fmt.Sprint(tc.want) && !(len(got) == 0 && len(tc.want) == 0) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+			if out.String() != tc.out {
+				t.Errorf("got %q, want %q", out.String(), tc.out)
+			}
+		})
+	}
+}
+
+func TestForthError(t *testing.T) {
+	for _, in := range []string{"nosuch", "drop", ": f if ;", "1 0 /", "1 abort\" x\"", "5 throw"} {
+		f := NewForth()
+		if err := f.Evaluate(in); err == nil {
+			t.Errorf("%v: want error", in)
+		}
+	}
+	f := NewForth()
+	if err := f.Evaluate("1 bye 2"); err != ErrBye {
+		t.Errorf("got %v, want %v", err, ErrBye)
+	}
+}
+
+// TestEForth metacompiles eForth, as gforth does with docs/j1eforth/j1.4th
+func TestEForth(t *testing.T) {
+	want, err := os.ReadFile("../eforth/j1e.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := filepath.Abs("../docs/j1eforth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	f := NewForth()
+	f.Dir = src
+	if err := f.Include("j1.4th"); err != ErrBye {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "j1.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("j1.bin differs from eforth/j1e.bin")
+	}
+}
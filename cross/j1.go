@@ -0,0 +1,832 @@
+package cross
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dim13/j1"
+)
+
+// target memory layout
+const (
+	flashSize = 0x10000 // bytes of target memory
+	flashMax  = 0x7fff  // highest legal target address
+	imageSize = 0x4000  // bytes of J1 memory image
+	sentinel  = 947947  // left on stack by target definitions
+)
+
+// Compiler for J1 targets. It is host Forth with the vocabularies of
+// docs/j1demo/firmware/crossj1.fs built in: j1assembler, metacompiler and
+// j1target. Sources may include crossj1.fs, the file is not read.
+type Compiler struct {
+	*Forth
+	// Optimize each target definition at ; unless it has inline data
+	Optimize bool
+
+	flash  []byte
+	labels map[int64]string
+
+	// start of current target definition, set if it has inline data
+	start  int64
+	inline bool
+
+	// host variables
+	tdp, tcompile, shadow int64
+	outfile               *word
+
+	// file name of last snap and address of its string in target
+	snapFile string
+	snapAddr int64
+
+	asm, meta, target, escape int
+}
+
+// NewCompiler in meta mode, as left by including crossj1.fs
+func NewCompiler() *Compiler {
+	c := &Compiler{
+		Forth:  NewForth(),
+		flash:  bytes.Repeat([]byte{0xff}, flashSize),
+		labels: make(map[int64]string),
+	}
+	c.Provided = map[string]bool{"crossj1.fs": true}
+	c.asm = c.Wordlist()
+	c.meta = c.Wordlist()
+	c.target = c.Wordlist()
+	c.escape = c.Wordlist()
+	c.forthWords()
+	c.asmWords()
+	c.metaWords()
+	c.modeMeta()
+	return c
+}
+
+// Image of target memory, as expected by Core.Write
+func (c *Compiler) Image() []byte {
+	return append([]byte(nil), c.flash[:imageSize]...)
+}
+
+// WriteListing of whole image, in format of disassemble-block
+func (c *Compiler) WriteListing(w io.Writer) error {
+	var b strings.Builder
+	for a := int64(0); a < imageSize; a += 2 {
+		b.WriteString(c.disassemble(a))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// define word in wordlist wid
+func (c *Compiler) define(wid int, name string, fn func(*Forth)) {
+	cur := c.current
+	c.current = wid
+	c.Define(name, fn)
+	c.current = cur
+}
+
+// variable in wordlist wid, returns its address
+func (c *Compiler) variable(wid int, name string) int64 {
+	cur := c.current
+	c.current = wid
+	w := c.create(name)
+	c.current = cur
+	c.comma(0)
+	return w.pfa
+}
+
+// import word into metacompiler, to make it visible in target mode
+func (c *Compiler) alias(name string) {
+	w := c.search(name, c.forth)
+	if w == nil {
+		w = c.search(name, c.asm)
+	}
+	c.define(c.meta, name, func(f *Forth) { f.execute(w) })
+}
+
+// evaluateString interprets text, as EVALUATE does
+func (c *Compiler) evaluateString(text string) {
+	c.include("evaluate", "", strings.NewReader(text))
+}
+
+// output goes to outfile, if set
+func (c *Compiler) out(b []byte) {
+	if fd := c.fetch(c.outfile.pfa); fd != 0 {
+		if _, err := c.file(fd).Write(b); err != nil {
+			c.throw(-37, "%v", err)
+		}
+		return
+	}
+	c.write(b)
+}
+
+// vocabulary words and output redirection of crossj1.fs
+func (c *Compiler) forthWords() {
+	c.outfile = c.create("outfile")
+	c.comma(0)
+	c.outfile.prim = func(f *Forth) { f.Push(f.fetch(c.outfile.pfa)) }
+	c.Define("type", func(f *Forth) {
+		n, a := f.Pop(), f.Pop()
+		c.out(f.bytes(a, n))
+	})
+	c.Define("emit", func(f *Forth) { c.out([]byte{byte(f.Pop())}) })
+	c.Define("cr", func(f *Forth) { c.out([]byte{'\n'}) })
+	c.Define("space", func(f *Forth) { c.out([]byte{' '}) })
+	c.Define("spaces", func(f *Forth) {
+		if n := f.Pop(); n > 0 {
+			c.out([]byte(strings.Repeat(" ", int(n))))
+		}
+	})
+	vocabulary := func(name string, wid int) {
+		c.Define(name, func(f *Forth) { f.setTop(wid) })
+	}
+	vocabulary("j1assembler", c.asm)
+	vocabulary("metacompiler", c.meta)
+	vocabulary("j1target", c.target)
+	c.Define("j1asm", func(*Forth) { c.modeAsm() })
+	c.Define("meta", func(*Forth) { c.modeMeta() })
+	c.Define("target", func(*Forth) { c.modeTarget() })
+}
+
+func (c *Compiler) modeAsm() {
+	c.order = []int{c.forth, c.asm, c.meta}
+	c.current = c.asm
+}
+
+func (c *Compiler) modeMeta() {
+	c.order = []int{c.forth, c.meta, c.asm, c.target}
+	c.current = c.meta
+}
+
+func (c *Compiler) modeTarget() {
+	c.order = []int{c.target, c.meta}
+	c.current = c.target
+}
+
+// target memory
+
+func (c *Compiler) there() int64     { return c.fetch(c.tdp) }
+func (c *Compiler) setThere(a int64) { c.store(c.tdp, a) }
+
+func (c *Compiler) legal(a int64) int64 {
+	if uint64(a) > flashMax {
+		c.throw(-2, "illegal address")
+	}
+	return a
+}
+
+func (c *Compiler) tcstore(a, v int64)    { c.flash[c.legal(a)] = byte(v) }
+func (c *Compiler) tcfetch(a int64) int64 { return int64(c.flash[c.legal(a)]) }
+
+func (c *Compiler) tstore(a, v int64) {
+	c.legal(a)
+	c.tcstore(a, v&0xff)
+	c.tcstore(a+1, v>>8)
+}
+
+func (c *Compiler) tfetch(a int64) int64 {
+	c.legal(a)
+	return c.tcfetch(a) | c.tcfetch(a+1)<<8
+}
+
+func (c *Compiler) talign() { c.setThere((c.there() + 1) & 0xfffe) }
+
+func (c *Compiler) tccomma(v int64) {
+	c.inline = true
+	c.tcstore(c.there(), v)
+	c.setThere(c.there() + 1)
+}
+
+func (c *Compiler) tcomma(v int64) {
+	c.tstore(c.there(), v)
+	c.setThere(c.there() + 2)
+}
+
+// data compiles v as cell which is not an instruction
+func (c *Compiler) data(v int64) {
+	c.inline = true
+	c.tcomma(v)
+}
+
+func (c *Compiler) atLabel() bool {
+	_, ok := c.labels[c.there()]
+	return ok
+}
+
+// setLabel at there, first label wins
+func (c *Compiler) setLabel(name string) {
+	if !c.atLabel() {
+		c.labels[c.there()] = name
+	}
+}
+
+// instructions
+
+const (
+	insJump   = 0x0000
+	insCond   = 0x2000
+	insCall   = 0x4000
+	insALU    = 0x6000
+	insLit    = 0x8000
+	insReturn = 0x1000 | 0x000c // R→PC and r-1
+	insInvert = 0x0600          // ~T
+)
+
+func (c *Compiler) alu(v int64)     { c.tcomma(v | insALU) }
+func (c *Compiler) ubranch(a int64) { c.tcomma(a>>1 | insJump) }
+func (c *Compiler) zbranch(a int64) { c.tcomma(a>>1 | insCond) }
+func (c *Compiler) scall(a int64)   { c.tcomma(a>>1 | insCall) }
+
+// lit compiles literal, values with bit 15 set are inverted
+func (c *Compiler) lit(v int64) {
+	if v&0x8000 != 0 {
+		c.lit(v ^ 0xffff)
+		c.alu(insInvert)
+		return
+	}
+	c.tcomma(v | insLit)
+}
+
+func (c *Compiler) tcompiling() bool { return c.fetch(c.tcompile) != 0 }
+
+func (c *Compiler) setTcompile(on bool) {
+	if on && c.tcompiling() {
+		c.throw(-2, "Already in compilation mode")
+	}
+	c.store(c.tcompile, -bool2cell(on))
+}
+
+// doNumber compiles n into host or target definition, or leaves it
+func (c *Compiler) doNumber(n int64) {
+	switch {
+	case c.compiling():
+		c.literal(n)
+	case c.tcompiling():
+		c.lit(n)
+	default:
+		c.Push(n)
+	}
+}
+
+// wordstr is next name, without consuming it
+func (c *Compiler) wordstr() string {
+	in := c.in()
+	s := c.word()
+	c.setIn(in)
+	return s
+}
+
+// tcreate defines word with target address a in its body, does runs on
+// execution
+func (c *Compiler) tcreate(a int64, does func(a int64)) *word {
+	w := c.create(c.name())
+	c.comma(a)
+	w.prim = func(f *Forth) { does(f.fetch(w.pfa)) }
+	return w
+}
+
+func (c *Compiler) asmWords() {
+	asm := func(name string, fn func(*Forth)) { c.define(c.asm, name, fn) }
+	asm("tcell", func(f *Forth) { f.Push(2) })
+	asm("tcells", func(f *Forth) { f.Push(f.Pop() * 2) })
+	asm("tcell+", func(f *Forth) { f.Push(f.Pop() + 2) })
+	c.tdp = c.variable(c.asm, "tdp")
+	asm("there", func(f *Forth) { f.Push(c.there()) })
+	asm("islegal", func(f *Forth) { c.legal(f.peek(0)) })
+	asm("tc!", func(f *Forth) {
+		a := f.Pop()
+		c.tcstore(a, f.Pop())
+	})
+	asm("tc@", func(f *Forth) { f.Push(c.tcfetch(f.Pop())) })
+	asm("t!", func(f *Forth) {
+		a := f.Pop()
+		c.tstore(a, f.Pop())
+	})
+	asm("t@", func(f *Forth) { f.Push(c.tfetch(f.Pop())) })
+	asm("talign", func(*Forth) { c.talign() })
+	asm("tc,", func(f *Forth) { c.tccomma(f.Pop()) })
+	asm("t,", func(f *Forth) { c.data(f.Pop()) })
+	asm("org", func(f *Forth) {
+		c.inline = true
+		c.setThere(f.Pop())
+	})
+	asm("atlabel?", func(f *Forth) { f.Push(bool2cell(c.atLabel())) })
+	asm("preserve", func(f *Forth) {
+		n, a := f.Pop(), f.Pop()
+		p := f.place(" " + f.str(a, n))
+		f.cstore(p, n)
+		f.Push(p)
+	})
+	asm("setlabel", func(f *Forth) {
+		n, a := f.Pop(), f.Pop()
+		c.setLabel(f.str(a, n))
+	})
+
+	asm("imm", func(f *Forth) { c.tcomma(f.Pop() | insLit) })
+	for i, name := range []string{
+		"T", "N", "T+N", "T&N", "T|N", "T^N", "~T", "N==T",
+		"N<T", "N>>T", "T-1", "rT", "[T]", "N<<T", "dsp", "Nu<T",
+	} {
+		v := int64(i) << 8
+		asm(name, func(f *Forth) { f.Push(v) })
+	}
+	for _, fl := range []struct {
+		name string
+		v    int64
+	}{
+		{"T->N", 0x0080},
+		{"T->R", 0x0040},
+		{"N->[T]", 0x0020},
+		{"d-1", 0x0003},
+		{"d+1", 0x0001},
+		{"r-1", 0x000c},
+		{"r-2", 0x0008},
+		{"r+1", 0x0004},
+	} {
+		v := fl.v
+		asm(fl.name, func(f *Forth) { f.Push(f.Pop() | v) })
+	}
+	asm("alu", func(f *Forth) { c.alu(f.Pop()) })
+	asm("return", func(*Forth) { c.alu(insReturn) })
+	asm("ubranch", func(f *Forth) { c.ubranch(f.Pop()) })
+	asm("0branch", func(f *Forth) { c.zbranch(f.Pop()) })
+	asm("scall", func(f *Forth) { c.scall(f.Pop()) })
+
+	asm("disassemble-line", func(f *Forth) {
+		a := f.Pop()
+		c.out([]byte(c.disassemble(a)))
+		f.Push(a + 2)
+	})
+	asm("disassemble-block", func(f *Forth) {
+		n, a := f.Pop(), f.Pop()
+		for i := int64(0); i < n; i++ {
+			c.out([]byte(c.disassemble(a)))
+			a += 2
+		}
+	})
+
+	c.tcompile = c.variable(c.asm, "tcompile")
+	asm("tcompile?", func(f *Forth) { f.Push(f.fetch(c.tcompile)) })
+	asm("+tcompile", func(*Forth) { c.setTcompile(true) })
+	asm("-tcompile", func(*Forth) { c.setTcompile(false) })
+	asm("(literal)", func(f *Forth) { c.lit(f.Pop()) })
+	asm("(t-constant)", func(f *Forth) {
+		if n := f.Pop(); c.tcompiling() {
+			c.lit(n)
+		} else {
+			f.Push(n)
+		}
+	})
+	asm("end-code", func(f *Forth) {
+		c.structured()
+		f.order = f.order[3:]
+	})
+}
+
+// structured checks that target definition is complete
+func (c *Compiler) structured() {
+	if c.Pop() != sentinel {
+		c.throw(-2, "Unstructured")
+	}
+}
+
+// tcolon starts target definition
+func (c *Compiler) tcolon() {
+	c.talign()
+	c.start, c.inline = c.there(), false
+	c.setLabel(c.wordstr())
+	c.tcreate(c.there(), func(a int64) {
+		if c.tcompiling() {
+			c.scall(a)
+		} else {
+			c.Push(a)
+		}
+	})
+	c.setTcompile(true)
+	c.Push(sentinel)
+}
+
+// tsemicolon ends target definition. A call before it becomes a jump, an
+// ALU instruction which leaves return stack alone gets R→PC. Neither is
+// done at a label, as some branch may end there.
+func (c *Compiler) tsemicolon() {
+	c.structured()
+	prev := c.there() - 2
+	switch ins := c.tfetch(prev); {
+	case c.atLabel():
+		c.alu(insReturn)
+	case ins&0xe000 == insCall:
+		c.tstore(prev, ins&0x1fff)
+	case ins&0xe000 == insALU && ins&0x004c == 0:
+		c.tstore(prev, ins|insReturn)
+	default:
+		c.alu(insReturn)
+	}
+	if c.Optimize && !c.inline {
+		c.optimize(c.start, c.there())
+	}
+	c.setTcompile(false)
+}
+
+// optimize target code from a up to end, labels within are kept and
+// moved, freed cells are erased
+func (c *Compiler) optimize(a, end int64) {
+	var code []j1.Instruction
+	for p := a; p < end; p += 2 {
+		code = append(code, j1.Decode(uint16(c.tfetch(p))))
+	}
+	var addrs []int64
+	var entries []uint16
+	for p := range c.labels {
+		if p > a && p <= end {
+			addrs = append(addrs, p)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	for _, p := range addrs {
+		entries = append(entries, uint16(p>>1))
+	}
+	code, entries = j1.Optimize(code, uint16(a>>1), entries...)
+	names := make([]string, len(addrs))
+	for i, p := range addrs {
+		names[i] = c.labels[p]
+		delete(c.labels, p)
+	}
+	for i, name := range names {
+		if _, ok := c.labels[int64(entries[i])<<1]; !ok {
+			c.labels[int64(entries[i])<<1] = name
+		}
+	}
+	for p := a; p < end; p += 2 {
+		c.tstore(p, 0xffff)
+	}
+	for i, ins := range code {
+		c.tstore(a+int64(2*i), int64(j1.Encode(ins)))
+	}
+	c.setThere(a + int64(2*len(code)))
+}
+
+// sliteral compiles counted string after call to sliteral, returns its
+// address
+func (c *Compiler) sliteral(s string) int64 {
+	c.evaluateString("sliteral")
+	a := c.there()
+	c.tccomma(int64(len(s)))
+	for i := 0; i < len(s); i++ {
+		c.tccomma(int64(s[i]))
+	}
+	c.talign()
+	return a
+}
+
+// resolve forward branch at orig to there
+func (c *Compiler) resolve(orig int64) {
+	c.tstore(orig, c.tfetch(orig)|c.there()>>1)
+}
+
+// number in base as eForth does: optional sign, a trailing dot compiles
+// low and high 16 bit of double
+func (c *Compiler) number(s string, base uint64) {
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+	var v uint64
+	for len(s) > 0 {
+		d, ok := digitValue(s[0])
+		if !ok || d >= base {
+			break
+		}
+		v = v*base + d
+		s = s[1:]
+	}
+	if neg {
+		v = -v
+	}
+	switch s {
+	case "":
+		c.doNumber(int64(v))
+	case ".":
+		c.doNumber(int64(v))
+		c.doNumber(int64(v >> 16))
+	default:
+		c.throw(-2, "bad number")
+	}
+}
+
+func (c *Compiler) metaWords() {
+	meta := func(name string, fn func(*Forth)) { c.define(c.meta, name, fn) }
+	meta("wordstr", func(f *Forth) {
+		s := c.wordstr()
+		f.Push(f.place(s))
+		f.Push(int64(len(s)))
+	})
+	meta("literal", func(f *Forth) { c.lit(f.Pop()) })
+	c.Immediate()
+	meta("2literal", func(f *Forth) {
+		hi := f.Pop()
+		c.lit(f.Pop())
+		c.lit(hi)
+	})
+	c.Immediate()
+	meta("call,", func(f *Forth) { c.scall(f.Pop()) })
+	meta("t:", func(*Forth) { c.tcolon() })
+	meta("t;", func(*Forth) { c.tsemicolon() })
+	meta("t;fallthru", func(*Forth) {
+		c.structured()
+		c.setTcompile(false)
+	})
+
+	c.shadow = c.variable(c.meta, "shadow-tcompile")
+	c.define(c.escape, "]", func(f *Forth) {
+		f.store(c.tcompile, f.fetch(c.shadow))
+		f.order = f.order[2:]
+	})
+	meta("[", func(f *Forth) {
+		f.store(c.shadow, f.fetch(c.tcompile))
+		c.setTcompile(false)
+		f.order = append([]int{c.escape, c.forth}, f.order...)
+	})
+	meta(":", func(*Forth) { c.tcolon() })
+	meta(";", func(*Forth) { c.tsemicolon() })
+	meta(";fallthru", func(*Forth) {
+		c.structured()
+		c.setTcompile(false)
+	})
+	meta(",", func(f *Forth) { c.data(f.Pop()) })
+	meta("c,", func(f *Forth) { c.tccomma(f.Pop()) })
+	meta("constant", func(f *Forth) {
+		c.tcreate(f.Pop(), func(n int64) {
+			if c.tcompiling() {
+				c.lit(n)
+			} else {
+				c.Push(n)
+			}
+		})
+		c.Immediate()
+	})
+	asmOn := func(f *Forth) {
+		c.setTcompile(false)
+		f.order = append([]int{c.asm, c.target, c.forth}, f.order...)
+	}
+	meta("]asm", asmOn)
+	meta("asm[", func(f *Forth) {
+		c.setTcompile(true)
+		f.order = f.order[3:]
+	})
+	meta("code", func(f *Forth) {
+		c.tcolon()
+		asmOn(f)
+	})
+
+	for _, name := range []string{"(", "\\", "meta", "org", "include", "[if]", "[else]", "[then]"} {
+		c.alias(name)
+	}
+	meta("do-number", func(f *Forth) { c.doNumber(f.Pop()) })
+	meta("[char]", func(f *Forth) { c.lit(int64(f.name()[0])) })
+	// target word is executed for its address
+	address := func(f *Forth) int64 {
+		w := f.tick(f.name())
+		on := f.fetch(c.tcompile)
+		f.store(c.tcompile, 0)
+		f.execute(w)
+		f.store(c.tcompile, on)
+		return f.Pop()
+	}
+	meta("[']", func(f *Forth) { c.lit(address(f)) })
+	meta("(sliteral--h)", func(f *Forth) {
+		n, a := f.Pop(), f.Pop()
+		f.Push(c.sliteral(f.str(a, n)))
+	})
+	meta("(sliteral)", func(f *Forth) {
+		n, a := f.Pop(), f.Pop()
+		c.sliteral(f.str(a, n))
+	})
+	meta("s\"", func(f *Forth) {
+		a, n := f.parse('"', false)
+		c.sliteral(f.str(a, n))
+	})
+	meta("s'", func(f *Forth) {
+		a, n := f.parse('\'', false)
+		c.sliteral(f.str(a, n))
+	})
+
+	data := func(a int64) { c.doNumber(a) }
+	meta("create", func(*Forth) {
+		c.setLabel(c.wordstr())
+		c.tcreate(c.there(), data)
+	})
+	meta("allot", func(f *Forth) {
+		c.inline = true
+		c.setThere(c.there() + f.Pop())
+	})
+	cells := func(n int64) func(*Forth) {
+		return func(*Forth) {
+			c.setLabel(c.wordstr())
+			c.tcreate(c.there(), data)
+			for i := int64(0); i < n; i++ {
+				c.tcomma(0)
+			}
+		}
+	}
+	meta("variable", cells(1))
+	meta("2variable", cells(2))
+	meta("createdoes", func(f *Forth) {
+		c.setLabel(c.wordstr())
+		w := c.tcreate(c.there(), nil)
+		xt := f.tick(f.name())
+		w.prim = func(f *Forth) {
+			c.lit(f.fetch(w.pfa))
+			f.execute(xt)
+		}
+	})
+	meta("jumptable", func(*Forth) {
+		c.setLabel(c.wordstr())
+		c.tcreate(c.there(), func(a int64) {
+			c.evaluateString("2*")
+			c.lit(a)
+			c.evaluateString("+ @")
+		})
+	})
+	meta("|", func(f *Forth) { c.data(address(f)) })
+	meta("',", func(f *Forth) { c.data(address(f)) })
+	meta("defer", func(*Forth) {
+		c.setLabel(c.wordstr())
+		c.tcreate(c.there(), func(a int64) {
+			if !c.tcompiling() {
+				c.Push(a)
+				return
+			}
+			c.doNumber(a)
+			c.evaluateString("@ execute")
+		})
+		c.tcomma(0)
+	})
+	meta("is", func(f *Forth) {
+		if c.tcompiling() {
+			c.doNumber(f.fetch(f.tick(f.name()).pfa))
+			c.evaluateString("! ")
+			return
+		}
+		a := address(f)
+		c.tstore(a, f.Pop())
+	})
+	meta("'", func(f *Forth) { f.execute(f.tick(f.name())) })
+	meta("value", func(f *Forth) {
+		c.setLabel(c.wordstr())
+		c.tcreate(c.there(), func(a int64) {
+			c.doNumber(a)
+			c.evaluateString("@")
+		})
+		c.tcomma(f.Pop())
+	})
+	meta("to", func(f *Forth) {
+		c.doNumber(f.fetch(f.tick(f.name()).pfa))
+		c.evaluateString("!")
+	})
+	array := func(scale string, k int64) func(*Forth) {
+		return func(f *Forth) {
+			c.setLabel(c.wordstr())
+			c.tcreate(c.there(), func(a int64) {
+				c.evaluateString(scale)
+				c.doNumber(a)
+				c.evaluateString("+")
+			})
+			for i := k * f.Pop(); i > 0; i-- {
+				c.tcomma(0)
+			}
+		}
+	}
+	meta("array", array("cells", 1))
+	meta("2array", array("2* cells", 2))
+
+	meta("d#", func(f *Forth) {
+		a, n := f.parse(' ', false)
+		c.number(f.str(a, n), 10)
+	})
+	meta("h#", func(f *Forth) {
+		a, n := f.parse(' ', false)
+		c.number(f.str(a, n), 16)
+	})
+
+	// conditionals
+	meta("if", func(f *Forth) {
+		f.Push(c.there())
+		c.zbranch(0)
+	})
+	meta("resolve", func(f *Forth) { c.resolve(f.Pop()) })
+	meta("then", func(f *Forth) {
+		c.resolve(f.Pop())
+		c.setLabel("(then)")
+	})
+	meta("else", func(f *Forth) {
+		orig := f.Pop()
+		f.Push(c.there())
+		c.ubranch(0)
+		c.resolve(orig)
+		c.setLabel("(else)")
+	})
+	meta("begin", func(f *Forth) {
+		c.setLabel("(begin)")
+		f.Push(c.there())
+	})
+	meta("again", func(f *Forth) { c.ubranch(f.Pop()) })
+	meta("until", func(f *Forth) { c.zbranch(f.Pop()) })
+	meta("while", func(f *Forth) {
+		f.Push(c.there())
+		c.zbranch(0)
+	})
+	meta("repeat", func(f *Forth) {
+		orig, dest := f.Pop(), f.Pop()
+		c.ubranch(dest)
+		c.resolve(orig)
+		c.setLabel("(repeat)")
+	})
+	do := func(prologue string) func(*Forth) {
+		return func(f *Forth) {
+			c.evaluateString(prologue)
+			f.Push(c.there())
+			c.setLabel("(do)")
+		}
+	}
+	meta("0do", do(">r d# 0 >r"))
+	meta("do", do("2>r"))
+	meta("loop", func(f *Forth) {
+		c.evaluateString("looptest")
+		c.zbranch(f.Pop())
+	})
+	meta("i", func(*Forth) { c.evaluateString("r@") })
+
+	// debugging aids compile source position
+	line := func() { c.lit(int64(c.sourceFile().line)) }
+	filename := func() {
+		name := c.sourceFile().name
+		if name != c.snapFile {
+			c.snapFile = name
+			c.snapAddr = c.sliteral(name)
+			return
+		}
+		c.lit(c.snapAddr + 1)
+		c.lit(c.tcfetch(c.snapAddr))
+	}
+	meta("line#", func(*Forth) { line() })
+	meta("getfilename", func(*Forth) { filename() })
+	meta("snap", func(*Forth) {
+		line()
+		filename()
+		c.evaluateString("(snap)")
+	})
+	c.Immediate()
+	meta("assert", func(f *Forth) {
+		if f.Pop() == 0 {
+			line()
+			c.sliteral(c.sourceFile().name)
+			c.evaluateString("(assert)")
+		}
+	})
+	c.Immediate()
+}
+
+// disassemble instruction at a, as disassemble-line does
+func (c *Compiler) disassemble(a int64) string {
+	base := uint64(c.fetch(addrBase))
+	var b strings.Builder
+	if l, ok := c.labels[a]; ok {
+		b.WriteString("\\ " + l + "\n")
+	}
+	ins := c.tfetch(a)
+	b.WriteString(digits(uint64(a), base, 4) + " " + digits(uint64(ins), base, 4) + " ")
+	b.WriteString(strings.Repeat(" ", 10))
+	loc := func(a int64) string {
+		if l, ok := c.labels[a]; ok {
+			return l + " "
+		}
+		return "$" + digits(uint64(a), base, 0) + " "
+	}
+	switch {
+	case ins&insLit != 0:
+		b.WriteString("LIT $" + digits(uint64(ins&0x7fff), base, 0) + " ")
+	case ins&0xe000 == insALU:
+		b.WriteString("ALU " + loc(ins))
+	case ins&0xe000 == insCall:
+		b.WriteString("CALL " + loc(ins&0x1fff<<1))
+	case ins&0xe000 == insCond:
+		b.WriteString("0BRANCH " + loc(ins&0x1fff<<1))
+	default:
+		b.WriteString("BRANCH " + loc(ins&0x1fff<<1))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// digits of v in base, the n lowest ones if n is set
+func digits(v, base uint64, n int) string {
+	s := formatUint(v, int64(base))
+	if n == 0 {
+		return s
+	}
+	if len(s) > n {
+		return s[len(s)-n:]
+	}
+	return strings.Repeat("0", n-len(s)) + s
+}
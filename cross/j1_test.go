@@ -0,0 +1,166 @@
+package cross
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dim13/j1"
+)
+
+// TestFirmware rebuilds j1demo firmware, main.fs writes its own outputs
+func TestFirmware(t *testing.T) {
+	testdata, err := filepath.Abs("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := filepath.Abs("../docs/j1demo/firmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	c := NewCompiler()
+	c.Dir = src
+	c.Out = new(bytes.Buffer)
+	// file names are compiled in, as given on include
+	if err := c.Include("main.fs"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"j1.bin", "j1.mem", "j1.lst"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.ReadFile(filepath.Join(testdata, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%v differs", name)
+		}
+	}
+}
+
+func TestCompiler(t *testing.T) {
+	testCases := []struct {
+		src  string
+		want []uint16
+	}{
+		{src: ": f dup + ;", want: []uint16{0x6081, 0x720f}},       // exit fused
+		{src: ": f >r ;", want: []uint16{0x6147, 0x700c}},          // touches R
+		{src: ": f dup ; : g f ;", want: []uint16{0x708d, 0x0000}}, // tail call
+		{src: ": f d# -1 ;", want: []uint16{0x8000, 0x760c}},       // inverted
+		{src: ": f h# 8000 d# 1 ;", want: []uint16{0xffff, 0x6600, 0x8001, 0x700c}},
+		{src: ": f if d# 1 then ;", want: []uint16{0x2002, 0x8001, 0x700c}},
+		{src: ": f begin dup while repeat ;", want: []uint16{0x6081, 0x2003, 0x0000, 0x700c}},
+		{src: ": f if d# 1 else d# 2 then ;", want: []uint16{0x2003, 0x8001, 0x0004, 0x8002, 0x700c}},
+		{src: "d# 5 constant five variable v : f five v ;", want: []uint16{0x0000, 0x8005, 0x8000, 0x700c}},
+		{src: "create t d# 7 , : f t ;", want: []uint16{0x0007, 0x8000, 0x700c}},
+		{src: "d# 3 value x : f x ;", want: []uint16{0x0003, 0x8000, 0x7c0c}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			c := NewCompiler()
+			c.Dir = "../docs/j1demo/firmware"
+			if err := c.Evaluate("include basewords.fs target " + tc.src); err != nil {
+				t.Fatal(err)
+			}
+			image := c.Image()
+			for i, want := range tc.want {
+				if got := uint16(image[2*i]) | uint16(image[2*i+1])<<8; got != want {
+					t.Errorf("cell %d: got %0.4X, want %0.4X", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+type console struct{}
+
+func (console) Read() uint16 { return 0 }
+func (console) Write(uint16) {}
+func (console) Len() uint16  { return 0 }
+func (console) Stop()        {}
+
+// TestOptimize runs words compiled with and without optimizer
+func TestOptimize(t *testing.T) {
+	src := `: double dup + ;
+		: f d# 1 d# 2 + d# 0 + double ;
+		: g begin dup while 1- repeat drop d# 5 d# 6 + ;
+		: h if f else g then ;
+		: k begin again ;`
+	run := func(optimize bool) (map[string][]uint16, int) {
+		c := NewCompiler()
+		c.Dir = "../docs/j1demo/firmware"
+		c.Optimize = optimize
+		if err := c.Evaluate("include basewords.fs target " + src); err != nil {
+			t.Fatal(err)
+		}
+		core := j1.New(console{})
+		image := c.Image()
+		core.Write(image)
+		res := make(map[string][]uint16)
+		for _, call := range []struct {
+			name string
+			args []uint16
+		}{
+			{"double", []uint16{21}},
+			{"f", nil},
+			{"g", []uint16{3}},
+			{"h", []uint16{0, 3}},
+			{"h", []uint16{0, 0}},
+		} {
+			if err := c.Evaluate("' " + call.name); err != nil {
+				t.Fatal(err)
+			}
+			v, err := core.CallAt(context.Background(), uint16(c.Pop()), call.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res[fmt.Sprint(call)] = v
+		}
+		n := len(image)
+		for image[n-1] == 0xff {
+			n--
+		}
+		return res, n
+	}
+	want, size := run(false)
+	got, optSize := run(true)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if optSize >= size {
+		t.Errorf("size: got %v, want less than %v", optSize, size)
+	}
+}
+
+func TestCompilerError(t *testing.T) {
+	testCases := []string{
+		": f ( missing ; ) if",
+		": f d# 1x ;",
+		"h# 8000 org : f ;",
+		": f : g ;",
+	}
+	for _, src := range testCases {
+		t.Run(src, func(t *testing.T) {
+			c := NewCompiler()
+			c.Dir = "../docs/j1demo/firmware"
+			if err := c.Evaluate("include basewords.fs target " + src + " ;"); err == nil {
+				t.Error("want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,914 @@
+package cross
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func bool2cell(b bool) int64 {
+	if b {
+		return -1
+	}
+	return 0
+}
+
+func (f *Forth) unary(name string, fn func(a int64) int64) {
+	f.Define(name, func(f *Forth) { f.Push(fn(f.Pop())) })
+}
+
+func (f *Forth) binary(name string, fn func(a, b int64) int64) {
+	f.Define(name, func(f *Forth) {
+		b, a := f.Pop(), f.Pop()
+		f.Push(fn(a, b))
+	})
+}
+
+func (f *Forth) constant(name string, v int64) {
+	f.Define(name, func(f *Forth) { f.Push(v) })
+}
+
+// control flow words put marks on control stack
+func (f *Forth) cpush(v int64) { f.ctl = append(f.ctl, v) }
+
+func (f *Forth) cpop() int64 {
+	n := len(f.ctl)
+	if n == 0 {
+		f.throw(-22, "control structure mismatch")
+	}
+	v := f.ctl[n-1]
+	f.ctl = f.ctl[:n-1]
+	return v
+}
+
+// resolve forward branch at orig to current position
+func (f *Forth) resolve(orig int64) {
+	f.def.code[orig].n = f.mark()
+}
+
+func (f *Forth) primitives() {
+	// stack
+	f.Define("dup", func(f *Forth) { f.Push(f.peek(0)) })
+	f.Define("?dup", func(f *Forth) {
+		if v := f.peek(0); v != 0 {
+			f.Push(v)
+		}
+	})
+	f.Define("drop", func(f *Forth) { f.Pop() })
+	f.Define("swap", func(f *Forth) {
+		b, a := f.Pop(), f.Pop()
+		f.Push(b)
+		f.Push(a)
+	})
+	f.Define("over", func(f *Forth) { f.Push(f.peek(1)) })
+	f.Define("nip", func(f *Forth) {
+		b := f.Pop()
+		f.Pop()
+		f.Push(b)
+	})
+	f.Define("tuck", func(f *Forth) {
+		b, a := f.Pop(), f.Pop()
+		f.Push(b)
+		f.Push(a)
+		f.Push(b)
+	})
+	f.Define("rot", func(f *Forth) {
+		c, b, a := f.Pop(), f.Pop(), f.Pop()
+		f.Push(b)
+		f.Push(c)
+		f.Push(a)
+	})
+	f.Define("-rot", func(f *Forth) {
+		c, b, a := f.Pop(), f.Pop(), f.Pop()
+		f.Push(c)
+		f.Push(a)
+		f.Push(b)
+	})
+	f.Define("pick", func(f *Forth) { f.Push(f.peek(int(f.Pop()))) })
+	f.Define("2dup", func(f *Forth) {
+		f.Push(f.peek(1))
+		f.Push(f.peek(1))
+	})
+	f.Define("2drop", func(f *Forth) {
+		f.Pop()
+		f.Pop()
+	})
+	f.Define("2swap", func(f *Forth) {
+		d, c, b, a := f.Pop(), f.Pop(), f.Pop(), f.Pop()
+		f.Push(c)
+		f.Push(d)
+		f.Push(a)
+		f.Push(b)
+	})
+	f.Define("2over", func(f *Forth) {
+		f.Push(f.peek(3))
+		f.Push(f.peek(3))
+	})
+	f.Define("depth", func(f *Forth) { f.Push(int64(len(f.stack))) })
+	f.Define(">r", func(f *Forth) { f.rpush(f.Pop()) })
+	f.Define("r>", func(f *Forth) { f.Push(f.rpop()) })
+	f.Define("r@", func(f *Forth) {
+		v := f.rpop()
+		f.rpush(v)
+		f.Push(v)
+	})
+	f.Define("rdrop", func(f *Forth) { f.rpop() })
+	f.Define("2>r", func(f *Forth) {
+		b, a := f.Pop(), f.Pop()
+		f.rpush(a)
+		f.rpush(b)
+	})
+	f.Define("2r>", func(f *Forth) {
+		b, a := f.rpop(), f.rpop()
+		f.Push(a)
+		f.Push(b)
+	})
+
+	// arithmetic and logic
+	f.binary("+", func(a, b int64) int64 { return a + b })
+	f.binary("-", func(a, b int64) int64 { return a - b })
+	f.binary("*", func(a, b int64) int64 { return a * b })
+	f.Define("/mod", func(f *Forth) {
+		b, a := f.Pop(), f.Pop()
+		q, r := f.divmod(a, b)
+		f.Push(r)
+		f.Push(q)
+	})
+	f.Define("/", func(f *Forth) {
+		b, a := f.Pop(), f.Pop()
+		q, _ := f.divmod(a, b)
+		f.Push(q)
+	})
+	f.Define("mod", func(f *Forth) {
+		b, a := f.Pop(), f.Pop()
+		_, r := f.divmod(a, b)
+		f.Push(r)
+	})
+	f.Define("*/", func(f *Forth) {
+		c, b, a := f.Pop(), f.Pop(), f.Pop()
+		q, _ := f.divmod(a*b, c)
+		f.Push(q)
+	})
+	f.binary("and", func(a, b int64) int64 { return a & b })
+	f.binary("or", func(a, b int64) int64 { return a | b })
+	f.binary("xor", func(a, b int64) int64 { return a ^ b })
+	f.binary("lshift", func(a, b int64) int64 { return int64(uint64(a) << uint64(b)) })
+	f.binary("rshift", func(a, b int64) int64 { return int64(uint64(a) >> uint64(b)) })
+	f.binary("min", func(a, b int64) int64 {
+		if a < b {
+			return a
+		}
+		return b
+	})
+	f.binary("max", func(a, b int64) int64 {
+		if a > b {
+			return a
+		}
+		return b
+	})
+	f.binary("=", func(a, b int64) int64 { return bool2cell(a == b) })
+	f.binary("<>", func(a, b int64) int64 { return bool2cell(a != b) })
+	f.binary("<", func(a, b int64) int64 { return bool2cell(a < b) })
+	f.binary(">", func(a, b int64) int64 { return bool2cell(a > b) })
+	f.binary("u<", func(a, b int64) int64 { return bool2cell(uint64(a) < uint64(b)) })
+	f.binary("u>", func(a, b int64) int64 { return bool2cell(uint64(a) > uint64(b)) })
+	f.unary("invert", func(a int64) int64 { return ^a })
+	f.unary("negate", func(a int64) int64 { return -a })
+	f.unary("abs", func(a int64) int64 {
+		if a < 0 {
+			return -a
+		}
+		return a
+	})
+	f.unary("1+", func(a int64) int64 { return a + 1 })
+	f.unary("1-", func(a int64) int64 { return a - 1 })
+	f.unary("2*", func(a int64) int64 { return a << 1 })
+	f.unary("2/", func(a int64) int64 { return a >> 1 })
+	f.unary("0=", func(a int64) int64 { return bool2cell(a == 0) })
+	f.unary("0<>", func(a int64) int64 { return bool2cell(a != 0) })
+	f.unary("0<", func(a int64) int64 { return bool2cell(a < 0) })
+	f.unary("0>", func(a int64) int64 { return bool2cell(a > 0) })
+	f.unary("cells", func(a int64) int64 { return a * cellSize })
+	f.unary("cell+", func(a int64) int64 { return a + cellSize })
+	f.unary("chars", func(a int64) int64 { return a })
+	f.unary("char+", func(a int64) int64 { return a + 1 })
+	f.unary("aligned", func(a int64) int64 { return (a + cellSize - 1) &^ (cellSize - 1) })
+	f.Define("s>d", func(f *Forth) {
+		a := f.Pop()
+		f.Push(a)
+		f.Push(a >> 63)
+	})
+	f.Define("d+", func(f *Forth) {
+		bh, bl, ah, al := f.Pop(), f.Pop(), f.Pop(), f.Pop()
+		lo, carry := bits.Add64(uint64(al), uint64(bl), 0)
+		hi, _ := bits.Add64(uint64(ah), uint64(bh), carry)
+		f.Push(int64(lo))
+		f.Push(int64(hi))
+	})
+	f.Define("dnegate", func(f *Forth) {
+		hi, lo := f.Pop(), f.Pop()
+		l, borrow := bits.Sub64(0, uint64(lo), 0)
+		h, _ := bits.Sub64(0, uint64(hi), borrow)
+		f.Push(int64(l))
+		f.Push(int64(h))
+	})
+	f.Define("d-", func(f *Forth) {
+		bh, bl, ah, al := f.Pop(), f.Pop(), f.Pop(), f.Pop()
+		lo, borrow := bits.Sub64(uint64(al), uint64(bl), 0)
+		hi, _ := bits.Sub64(uint64(ah), uint64(bh), borrow)
+		f.Push(int64(lo))
+		f.Push(int64(hi))
+	})
+	f.constant("true", -1)
+	f.constant("false", 0)
+	f.constant("bl", ' ')
+	f.constant("cell", cellSize)
+
+	// memory
+	f.Define("@", func(f *Forth) { f.Push(f.fetch(f.Pop())) })
+	f.Define("!", func(f *Forth) {
+		a := f.Pop()
+		f.store(a, f.Pop())
+	})
+	f.Define("+!", func(f *Forth) {
+		a := f.Pop()
+		f.store(a, f.fetch(a)+f.Pop())
+	})
+	f.Define("c@", func(f *Forth) { f.Push(f.cfetch(f.Pop())) })
+	f.Define("c!", func(f *Forth) {
+		a := f.Pop()
+		f.cstore(a, f.Pop())
+	})
+	f.Define("2@", func(f *Forth) {
+		a := f.Pop()
+		f.Push(f.fetch(a + cellSize))
+		f.Push(f.fetch(a))
+	})
+	f.Define("2!", func(f *Forth) {
+		a := f.Pop()
+		f.store(a, f.Pop())
+		f.store(a+cellSize, f.Pop())
+	})
+	f.Define("count", func(f *Forth) {
+		a := f.Pop()
+		f.Push(a + 1)
+		f.Push(f.cfetch(a))
+	})
+	f.Define("here", func(f *Forth) { f.Push(f.here()) })
+	f.Define("allot", func(f *Forth) { f.allot(f.Pop()) })
+	f.Define("align", func(f *Forth) { f.allot((cellSize - f.here()%cellSize) % cellSize) })
+	f.Define(",", func(f *Forth) { f.comma(f.Pop()) })
+	f.Define("c,", func(f *Forth) {
+		f.cstore(f.here(), f.Pop())
+		f.allot(1)
+	})
+	f.Define("fill", func(f *Forth) {
+		c, n, a := f.Pop(), f.Pop(), f.Pop()
+		b := f.bytes(a, n)
+		for i := range b {
+			b[i] = byte(c)
+		}
+	})
+	f.Define("erase", func(f *Forth) {
+		n, a := f.Pop(), f.Pop()
+		b := f.bytes(a, n)
+		for i := range b {
+			b[i] = 0
+		}
+	})
+	move := func(f *Forth) {
+		n, dst, src := f.Pop(), f.Pop(), f.Pop()
+		copy(f.bytes(dst, n), f.bytes(src, n))
+	}
+	f.Define("move", move)
+	f.Define("cmove", func(f *Forth) {
+		n, dst, src := f.Pop(), f.Pop(), f.Pop()
+		for i := int64(0); i < n; i++ {
+			f.cstore(dst+i, f.cfetch(src+i))
+		}
+	})
+	f.Define("cmove>", move)
+	f.Define("pad", func(f *Forth) { f.Push(addrPad) })
+	f.Define("state", func(f *Forth) { f.Push(addrState) })
+	f.Define("base", func(f *Forth) { f.Push(addrBase) })
+	f.Define(">in", func(f *Forth) { f.Push(addrIn) })
+	f.Define("warnings", func(f *Forth) { f.Push(addrWarn) })
+	f.Define("hex", func(f *Forth) { f.store(addrBase, 16) })
+	f.Define("decimal", func(f *Forth) { f.store(addrBase, 10) })
+	f.Define("compare", func(f *Forth) {
+		n2, a2, n1, a1 := f.Pop(), f.Pop(), f.Pop(), f.Pop()
+		f.Push(int64(strings.Compare(f.str(a1, n1), f.str(a2, n2))))
+	})
+	f.Define("/string", func(f *Forth) {
+		k, n, a := f.Pop(), f.Pop(), f.Pop()
+		f.Push(a + k)
+		f.Push(n - k)
+	})
+
+	// output
+	f.Define("emit", func(f *Forth) { f.write([]byte{byte(f.Pop())}) })
+	f.Define("type", func(f *Forth) {
+		n, a := f.Pop(), f.Pop()
+		f.write(f.bytes(a, n))
+	})
+	f.Define("cr", func(f *Forth) { f.write([]byte{'\n'}) })
+	f.Define("space", func(f *Forth) { f.write([]byte{' '}) })
+	f.Define("spaces", func(f *Forth) {
+		if n := f.Pop(); n > 0 {
+			f.write([]byte(strings.Repeat(" ", int(n))))
+		}
+	})
+	f.Define(".", func(f *Forth) { f.write([]byte(f.format(f.Pop()) + " ")) })
+	f.Define("u.", func(f *Forth) {
+		f.write([]byte(formatUint(uint64(f.Pop()), f.fetch(addrBase)) + " "))
+	})
+	f.Define(".s", func(f *Forth) {
+		s := fmt.Sprintf("<%d> ", len(f.stack))
+		for _, v := range f.stack {
+			s += f.format(v) + " "
+		}
+		f.write([]byte(s))
+	})
+
+	// pictured numeric output works on doubles, high cell is ignored
+	hold := func(f *Forth, c byte) {
+		p := f.fetch(addrHold) - 1
+		if p < addrWord+0x100 {
+			f.throw(-17, "pictured numeric output string overflow")
+		}
+		f.cstore(p, int64(c))
+		f.store(addrHold, p)
+	}
+	digit := func(f *Forth) {
+		hi, lo := uint64(f.Pop()), uint64(f.Pop())
+		base := uint64(f.fetch(addrBase))
+		qhi := hi / base
+		qlo, r := bits.Div64(hi%base, lo, base)
+		c := byte('0' + r)
+		if r > 9 {
+			c = byte('A' + r - 10)
+		}
+		hold(f, c)
+		f.Push(int64(qlo))
+		f.Push(int64(qhi))
+	}
+	f.Define("<#", func(f *Forth) { f.store(addrHold, addrHold) })
+	f.Define("hold", func(f *Forth) { hold(f, byte(f.Pop())) })
+	f.Define("#", digit)
+	f.Define("#s", func(f *Forth) {
+		digit(f)
+		for f.peek(0) != 0 || f.peek(1) != 0 {
+			digit(f)
+		}
+	})
+	f.Define("sign", func(f *Forth) {
+		if f.Pop() < 0 {
+			hold(f, '-')
+		}
+	})
+	f.Define("#>", func(f *Forth) {
+		f.Pop()
+		f.Pop()
+		p := f.fetch(addrHold)
+		f.Push(p)
+		f.Push(addrHold - p)
+	})
+
+	// parsing
+	f.Define("parse", func(f *Forth) {
+		a, n := f.parse(byte(f.Pop()), false)
+		f.Push(a)
+		f.Push(n)
+	})
+	parseName := func(f *Forth) {
+		a, n := f.parse(' ', true)
+		f.Push(a)
+		f.Push(n)
+	}
+	f.Define("parse-word", parseName)
+	f.Define("parse-name", parseName)
+	f.Define("word", func(f *Forth) {
+		a, n := f.parse(byte(f.Pop()), true)
+		if n > 0xff {
+			f.throw(-18, "parsed string overflow")
+		}
+		copy(f.mem[addrWord+1:], f.bytes(a, n))
+		f.cstore(addrWord, n)
+		f.cstore(addrWord+1+n, ' ')
+		f.Push(addrWord)
+	})
+	f.Define("source", func(f *Forth) {
+		a, n := f.source()
+		f.Push(a)
+		f.Push(n)
+	})
+	f.Define("refill", func(f *Forth) { f.Push(bool2cell(f.refill())) })
+	f.Define("sourcefilename", func(f *Forth) {
+		s := f.sourceFile()
+		if s.named == 0 {
+			s.named = f.place(s.name)
+		}
+		f.Push(s.named)
+		f.Push(int64(len(s.name)))
+	})
+	f.Define("sourceline#", func(f *Forth) { f.Push(int64(f.sourceFile().line)) })
+	f.Define(">number", func(f *Forth) {
+		n, a, hi, lo := f.Pop(), f.Pop(), uint64(f.Pop()), uint64(f.Pop())
+		base := uint64(f.fetch(addrBase))
+		for ; n > 0; a, n = a+1, n-1 {
+			d, ok := digitValue(byte(f.cfetch(a)))
+			if !ok || d >= base {
+				break
+			}
+			h1, l1 := bits.Mul64(lo, base)
+			var c uint64
+			lo, c = bits.Add64(l1, d, 0)
+			hi = hi*base + h1 + c
+		}
+		f.Push(int64(lo))
+		f.Push(int64(hi))
+		f.Push(a)
+		f.Push(n)
+	})
+	f.Define("char", func(f *Forth) { f.Push(int64(f.name()[0])) })
+	f.Define("[char]", func(f *Forth) { f.literal(int64(f.name()[0])) })
+	f.Immediate()
+	f.Define("(", func(f *Forth) { f.comment() })
+	f.Immediate()
+	f.Define("\\", func(f *Forth) {
+		_, n := f.source()
+		f.setIn(n)
+	})
+	f.Immediate()
+	f.Define(".(", func(f *Forth) {
+		a, n := f.parse(')', false)
+		f.write(f.bytes(a, n))
+	})
+	f.Immediate()
+	f.Define("[if]", func(f *Forth) {
+		if f.Pop() == 0 {
+			f.skip(true)
+		}
+	})
+	f.Immediate()
+	f.Define("[else]", func(f *Forth) { f.skip(false) })
+	f.Immediate()
+	f.Define("[then]", func(f *Forth) {})
+	f.Immediate()
+	f.Define("[defined]", func(f *Forth) { f.Push(bool2cell(f.find(f.name()) != nil)) })
+	f.Immediate()
+	f.Define("[undefined]", func(f *Forth) { f.Push(bool2cell(f.find(f.name()) == nil)) })
+	f.Immediate()
+	f.Define("evaluate", func(f *Forth) {
+		n, a := f.Pop(), f.Pop()
+		f.evaluate(a, n)
+	})
+	f.Define("include", func(f *Forth) { f.included(f.name()) })
+	f.Define("included", func(f *Forth) {
+		n, a := f.Pop(), f.Pop()
+		f.included(f.str(a, n))
+	})
+	f.Define("s\"", func(f *Forth) {
+		a, n := f.parse('"', false)
+		if f.compiling() {
+			a = f.place(f.str(a, n))
+			f.literal(a)
+			f.literal(n)
+			return
+		}
+		// interpreted strings get own buffer, as input line is reused
+		copy(f.mem[addrPad:], f.bytes(a, n))
+		f.Push(addrPad)
+		f.Push(n)
+	})
+	f.Immediate()
+	f.Define(".\"", func(f *Forth) {
+		a, n := f.parse('"', false)
+		f.literal(f.place(f.str(a, n)))
+		f.literal(n)
+		f.compile(inst{op: opCall, w: f.search("type", f.forth)})
+	})
+	f.Immediate()
+
+	// dictionary
+	f.Define("'", func(f *Forth) { f.Push(f.tick(f.name()).xt) })
+	f.Define("[']", func(f *Forth) { f.literal(f.tick(f.name()).xt) })
+	f.Immediate()
+	f.Define("execute", func(f *Forth) { f.execute(f.xt(f.Pop())) })
+	f.Define(">body", func(f *Forth) { f.Push(f.xt(f.Pop()).pfa) })
+	f.Define("compile,", func(f *Forth) { f.compile(inst{op: opCall, w: f.xt(f.Pop())}) })
+	f.Define("literal", func(f *Forth) { f.literal(f.Pop()) })
+	f.Immediate()
+	f.Define("postpone", func(f *Forth) {
+		w := f.tick(f.name())
+		if w.immediate {
+			f.compile(inst{op: opCall, w: w})
+		} else {
+			f.compile(inst{op: opCompile, w: w})
+		}
+	})
+	f.Immediate()
+	f.Define("immediate", func(f *Forth) { f.Immediate() })
+	f.Define("[", func(f *Forth) { f.store(addrState, 0) })
+	f.Immediate()
+	f.Define("]", func(f *Forth) { f.store(addrState, -1) })
+	f.Define(":", func(f *Forth) {
+		f.def = f.newWord(f.name())
+		f.defWid = f.current
+		f.def.colon = true
+		f.store(addrState, -1)
+		f.ctl = nil
+	})
+	f.Define(":noname", func(f *Forth) {
+		f.def = f.newWord("")
+		f.def.colon = true
+		f.store(addrState, -1)
+		f.ctl = nil
+		f.Push(f.def.xt)
+	})
+	f.Define(";", func(f *Forth) {
+		if len(f.ctl) != 0 {
+			f.throw(-22, "control structure mismatch")
+		}
+		f.compile(inst{op: opExit})
+		if f.def.name != "" {
+			f.reveal(f.def, f.defWid)
+		}
+		f.def = nil
+		f.store(addrState, 0)
+	})
+	f.Immediate()
+	f.Define("recurse", func(f *Forth) { f.compile(inst{op: opCall, w: f.def}) })
+	f.Immediate()
+	f.Define("exit", func(f *Forth) { f.compile(inst{op: opExit}) })
+	f.Immediate()
+	f.Define("does>", func(f *Forth) { f.compile(inst{op: opDoes}) })
+	f.Immediate()
+	f.Define("create", func(f *Forth) { f.create(f.name()) })
+	f.Define("variable", func(f *Forth) {
+		f.create(f.name())
+		f.comma(0)
+	})
+	f.Define("constant", func(f *Forth) {
+		w := f.create(f.name())
+		v := f.Pop()
+		f.comma(v)
+		w.prim = func(f *Forth) { f.Push(v) }
+	})
+	f.Define("2constant", func(f *Forth) {
+		w := f.create(f.name())
+		hi, lo := f.Pop(), f.Pop()
+		f.comma(hi)
+		f.comma(lo)
+		w.prim = func(f *Forth) {
+			f.Push(lo)
+			f.Push(hi)
+		}
+	})
+	f.Define("value", func(f *Forth) {
+		w := f.create(f.name())
+		f.comma(f.Pop())
+		w.prim = func(f *Forth) { f.Push(f.fetch(w.pfa)) }
+	})
+	f.Define("to", func(f *Forth) {
+		w := f.tick(f.name())
+		if f.compiling() {
+			f.literal(w.pfa)
+			f.compile(inst{op: opCall, w: f.search("!", f.forth)})
+			return
+		}
+		f.store(w.pfa, f.Pop())
+	})
+	f.Immediate()
+
+	// control structures
+	f.Define("if", func(f *Forth) {
+		f.cpush(f.mark())
+		f.compile(inst{op: op0Branch})
+	})
+	f.Immediate()
+	f.Define("ahead", func(f *Forth) {
+		f.cpush(f.mark())
+		f.compile(inst{op: opBranch})
+	})
+	f.Immediate()
+	f.Define("else", func(f *Forth) {
+		orig := f.cpop()
+		f.cpush(f.mark())
+		f.compile(inst{op: opBranch})
+		f.resolve(orig)
+	})
+	f.Immediate()
+	f.Define("then", func(f *Forth) { f.resolve(f.cpop()) })
+	f.Immediate()
+	f.Define("begin", func(f *Forth) { f.cpush(f.mark()) })
+	f.Immediate()
+	f.Define("again", func(f *Forth) { f.compile(inst{op: opBranch, n: f.cpop()}) })
+	f.Immediate()
+	f.Define("until", func(f *Forth) { f.compile(inst{op: op0Branch, n: f.cpop()}) })
+	f.Immediate()
+	f.Define("while", func(f *Forth) {
+		dest := f.cpop()
+		f.cpush(f.mark())
+		f.cpush(dest)
+		f.compile(inst{op: op0Branch})
+	})
+	f.Immediate()
+	f.Define("repeat", func(f *Forth) {
+		f.compile(inst{op: opBranch, n: f.cpop()})
+		f.resolve(f.cpop())
+	})
+	f.Immediate()
+	do := func(o op) func(*Forth) {
+		return func(f *Forth) {
+			f.leaves = append(f.leaves, nil)
+			f.cpush(f.mark())
+			f.compile(inst{op: o})
+		}
+	}
+	f.Define("do", do(opDo))
+	f.Immediate()
+	f.Define("?do", do(opQDo))
+	f.Immediate()
+	loop := func(o op) func(*Forth) {
+		return func(f *Forth) {
+			dest := f.cpop()
+			f.compile(inst{op: o, n: dest + 1})
+			f.def.code[dest].n = f.mark()
+			n := len(f.leaves)
+			if n == 0 {
+				f.throw(-22, "control structure mismatch")
+			}
+			for _, orig := range f.leaves[n-1] {
+				f.resolve(orig)
+			}
+			f.leaves = f.leaves[:n-1]
+		}
+	}
+	f.Define("loop", loop(opLoop))
+	f.Immediate()
+	f.Define("+loop", loop(opPlusLoop))
+	f.Immediate()
+	f.Define("leave", func(f *Forth) {
+		n := len(f.leaves)
+		if n == 0 {
+			f.throw(-22, "control structure mismatch")
+		}
+		f.leaves[n-1] = append(f.leaves[n-1], f.mark())
+		f.compile(inst{op: opLeave})
+	})
+	f.Immediate()
+	f.Define("unloop", func(f *Forth) {
+		f.rpop()
+		f.rpop()
+	})
+	// for runs loop u+1 times, as in gforth
+	f.Define("for", func(f *Forth) {
+		f.compile(inst{op: opCall, w: f.search(">r", f.forth)})
+		f.cpush(f.mark())
+	})
+	f.Immediate()
+	f.Define("next", func(f *Forth) { f.compile(inst{op: opNext, n: f.cpop()}) })
+	f.Immediate()
+	f.Define("i", func(f *Forth) { f.Push(f.rindex(0)) })
+	f.Define("j", func(f *Forth) { f.Push(f.rindex(2)) })
+
+	// wordlists
+	f.Define("wordlist", func(f *Forth) { f.Push(int64(f.Wordlist())) })
+	f.constant("forth-wordlist", int64(f.forth))
+	f.Define("get-current", func(f *Forth) { f.Push(int64(f.current)) })
+	f.Define("set-current", func(f *Forth) { f.current = f.wid(f.Pop()) })
+	f.Define("get-order", func(f *Forth) {
+		for i := len(f.order) - 1; i >= 0; i-- {
+			f.Push(int64(f.order[i]))
+		}
+		f.Push(int64(len(f.order)))
+	})
+	f.Define("set-order", func(f *Forth) {
+		n := f.Pop()
+		if n == -1 {
+			f.order = []int{f.forth}
+			return
+		}
+		order := make([]int, n)
+		for i := range order {
+			order[i] = f.wid(f.Pop())
+		}
+		f.order = order
+	})
+	f.Define("only", func(f *Forth) { f.order = []int{f.forth} })
+	f.Define("also", func(f *Forth) {
+		if len(f.order) == 0 {
+			f.throw(-50, "search-order underflow")
+		}
+		f.order = append([]int{f.order[0]}, f.order...)
+	})
+	f.Define("previous", func(f *Forth) {
+		if len(f.order) == 0 {
+			f.throw(-50, "search-order underflow")
+		}
+		f.order = f.order[1:]
+	})
+	f.Define("forth", func(f *Forth) { f.setTop(f.forth) })
+	f.Define("definitions", func(f *Forth) {
+		if len(f.order) > 0 {
+			f.current = f.order[0]
+		}
+	})
+	f.Define("vocabulary", func(f *Forth) {
+		w := f.create(f.name())
+		wid := f.Wordlist()
+		w.prim = func(f *Forth) { f.setTop(wid) }
+	})
+	f.Define("search-wordlist", func(f *Forth) {
+		wid, n, a := f.wid(f.Pop()), f.Pop(), f.Pop()
+		w := f.search(f.str(a, n), wid)
+		switch {
+		case w == nil:
+			f.Push(0)
+		case w.immediate:
+			f.Push(w.xt)
+			f.Push(1)
+		default:
+			f.Push(w.xt)
+			f.Push(-1)
+		}
+	})
+
+	// errors
+	f.Define("throw", func(f *Forth) {
+		if v := f.Pop(); v != 0 {
+			f.throw(v, "")
+		}
+	})
+	f.Define("abort", func(f *Forth) { f.throw(-1, "aborted") })
+	f.Define("abort\"", func(f *Forth) {
+		a, n := f.parse('"', false)
+		msg := f.str(a, n)
+		w := f.newWord("")
+		w.prim = func(f *Forth) {
+			if f.Pop() != 0 {
+				f.throw(-2, "%v", msg)
+			}
+		}
+		f.compile(inst{op: opCall, w: w})
+	})
+	f.Immediate()
+	f.Define("bye", func(f *Forth) { panic(ErrBye) })
+
+	// floating point, on separate stack
+	f.Define("fdup", func(f *Forth) {
+		r := f.fpop()
+		f.fpush(r)
+		f.fpush(r)
+	})
+	f.Define("fdrop", func(f *Forth) { f.fpop() })
+	f.Define("fswap", func(f *Forth) {
+		b, a := f.fpop(), f.fpop()
+		f.fpush(b)
+		f.fpush(a)
+	})
+	fbinary := func(name string, fn func(a, b float64) float64) {
+		f.Define(name, func(f *Forth) {
+			b, a := f.fpop(), f.fpop()
+			f.fpush(fn(a, b))
+		})
+	}
+	fbinary("f+", func(a, b float64) float64 { return a + b })
+	fbinary("f-", func(a, b float64) float64 { return a - b })
+	fbinary("f*", func(a, b float64) float64 { return a * b })
+	fbinary("f/", func(a, b float64) float64 { return a / b })
+	funary := func(name string, fn func(float64) float64) {
+		f.Define(name, func(f *Forth) { f.fpush(fn(f.fpop())) })
+	}
+	funary("fnegate", func(a float64) float64 { return -a })
+	funary("fsqrt", math.Sqrt)
+	funary("fsin", math.Sin)
+	funary("fcos", math.Cos)
+	f.Define("pi", func(f *Forth) { f.fpush(math.Pi) })
+	f.Define("s>f", func(f *Forth) { f.fpush(float64(f.Pop())) })
+	f.Define("d>f", func(f *Forth) {
+		hi, lo := f.Pop(), f.Pop()
+		f.fpush(float64(hi)*(1<<64) + float64(uint64(lo)))
+	})
+	// conversion truncates, as in C
+	f.Define("f>d", func(f *Forth) {
+		r := math.Trunc(f.fpop())
+		hi := math.Floor(r / (1 << 64))
+		f.Push(int64(uint64(r - hi*(1<<64))))
+		f.Push(int64(hi))
+	})
+	f.Define("f>s", func(f *Forth) { f.Push(int64(f.fpop())) })
+	f.Define("f.", func(f *Forth) {
+		f.write([]byte(strconv.FormatFloat(f.fpop(), 'g', -1, 64) + " "))
+	})
+
+	// files
+	f.constant("r/o", int64(os.O_RDONLY))
+	f.constant("w/o", int64(os.O_WRONLY))
+	f.constant("r/w", int64(os.O_RDWR))
+	f.unary("bin", func(a int64) int64 { return a })
+	f.Define("create-file", func(f *Forth) {
+		fam, n, a := f.Pop(), f.Pop(), f.Pop()
+		fd, err := os.OpenFile(f.str(a, n), int(fam)|os.O_CREATE|os.O_TRUNC, 0644)
+		f.Push(f.addFile(fd))
+		f.Push(ior(err))
+	})
+	f.Define("open-file", func(f *Forth) {
+		fam, n, a := f.Pop(), f.Pop(), f.Pop()
+		fd, err := os.OpenFile(f.str(a, n), int(fam), 0)
+		f.Push(f.addFile(fd))
+		f.Push(ior(err))
+	})
+	f.Define("close-file", func(f *Forth) {
+		v := f.Pop()
+		err := f.file(v).Close()
+		f.files[v-1] = nil
+		f.Push(ior(err))
+	})
+	f.Define("write-file", func(f *Forth) {
+		fd, n, a := f.file(f.Pop()), f.Pop(), f.Pop()
+		_, err := fd.Write(f.bytes(a, n))
+		f.Push(ior(err))
+	})
+	f.Define("write-line", func(f *Forth) {
+		fd, n, a := f.file(f.Pop()), f.Pop(), f.Pop()
+		_, err := fd.Write(append(append([]byte(nil), f.bytes(a, n)...), '\n'))
+		f.Push(ior(err))
+	})
+}
+
+// digitValue of c in any base up to 36
+func digitValue(c byte) (uint64, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return uint64(c - '0'), true
+	case c >= 'a' && c <= 'z':
+		return uint64(c-'a') + 10, true
+	case c >= 'A' && c <= 'Z':
+		return uint64(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+func (f *Forth) divmod(a, b int64) (int64, int64) {
+	if b == 0 {
+		f.throw(-10, "division by zero")
+	}
+	// floored division, as gforth does
+	q, r := a/b, a%b
+	if r != 0 && (r < 0) != (b < 0) {
+		q--
+		r += b
+	}
+	return q, r
+}
+
+func (f *Forth) rindex(i int) int64 {
+	n := len(f.rstack)
+	if n < i+2 {
+		f.throw(-6, "loop index outside of loop")
+	}
+	return f.rstack[n-1-i]
+}
+
+func (f *Forth) setTop(wid int) {
+	if len(f.order) == 0 {
+		f.order = []int{wid}
+		return
+	}
+	f.order[0] = wid
+}
+
+// create word returning its data field address
+func (f *Forth) create(name string) *word {
+	w := f.newWord(name)
+	w.pfa = f.here()
+	f.reveal(w, f.current)
+	return w
+}
+
+func (f *Forth) addFile(fd *os.File) int64 {
+	if fd == nil {
+		return 0
+	}
+	f.files = append(f.files, fd)
+	return int64(len(f.files))
+}
+
+func (f *Forth) format(v int64) string {
+	if v < 0 {
+		return "-" + formatUint(uint64(-v), f.fetch(addrBase))
+	}
+	return formatUint(uint64(v), f.fetch(addrBase))
+}
+
+func formatUint(v uint64, base int64) string {
+	if base < 2 || base > 36 {
+		base = 10
+	}
+	return strings.ToUpper(strconv.FormatUint(v, int(base)))
+}
@@ -0,0 +1,26 @@
+( block storage through Block device, see block.go )
+( single buffer: block buffer update flush )
+hex
+7020 constant bnum
+7022 constant bcmd
+7024 constant bdata
+7026 constant bptr
+400 constant b/buf
+create bbuf b/buf allot
+variable bcur -1 bcur !
+variable bdirty
+: flush ( -- )
+   bdirty @ if
+      0 bptr ! bbuf b/buf 2/ for aft dup @ bdata ! cell+ then next drop
+      bcur @ bnum ! 2 bcmd ! bcmd @ abort" block write"
+      0 bdirty !
+   then ;
+: buffer ( u -- a )
+   dup bcur @ = if drop bbuf exit then
+   flush bcur ! bbuf ;
+: block ( u -- a )
+   dup bcur @ = if drop bbuf exit then
+   flush dup bnum ! 1 bcmd !
+   bcmd @ if -1 bcur ! -1 abort" block read" then
+   bcur ! bbuf b/buf 2/ for aft bdata @ over ! cell+ then next drop bbuf ;
+: update ( -- ) -1 bdirty ! ;
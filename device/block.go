@@ -0,0 +1,116 @@
+package device
+
+import (
+	"errors"
+	"io"
+)
+
+// Block registers, relative to base address
+//
+//	offset  read                write
+//	0       block number        block number
+//	2       status              command
+//	4       cell at pointer     cell at pointer
+//	6       pointer             pointer
+//
+// Pointer is a byte offset in the buffer, it advances by one cell on every
+// data access and is reset by commands.
+const (
+	BlockNum  = 0
+	BlockCmd  = 2
+	BlockData = 4
+	BlockPtr  = 6
+	BlockSize = 8 // size of register window
+)
+
+// Block commands
+const (
+	BlockRead  = 1 // read block into buffer
+	BlockWrite = 2 // write buffer to block
+)
+
+// Block status
+const (
+	BlockOK     = 0
+	BlockFailed = 1
+	BlockBadCmd = 2
+)
+
+// BlockLen is size of Forth block
+const BlockLen = 1024
+
+// Storage backing block device, *os.File will do
+type Storage interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// Block maps 1 KiB Forth blocks to storage through a single buffer
+type Block struct {
+	storage Storage
+	buf     [BlockLen]byte
+	num     uint16
+	ptr     uint16
+	status  uint16
+}
+
+// NewBlock device on storage
+func NewBlock(s Storage) *Block {
+	return &Block{storage: s}
+}
+
+// Read register
+func (b *Block) Read(addr uint16) uint16 {
+	switch addr {
+	case BlockNum:
+		return b.num
+	case BlockCmd:
+		return b.status
+	case BlockData:
+		p := b.ptr % BlockLen
+		b.ptr = (p + 2) % BlockLen
+		return uint16(b.buf[p]) | uint16(b.buf[p+1])<<8
+	case BlockPtr:
+		return b.ptr
+	}
+	return 0
+}
+
+// Write register
+func (b *Block) Write(addr, value uint16) {
+	switch addr {
+	case BlockNum:
+		b.num = value
+	case BlockCmd:
+		b.ptr = 0
+		b.status = b.command(value)
+	case BlockData:
+		p := b.ptr % BlockLen
+		b.ptr = (p + 2) % BlockLen
+		b.buf[p], b.buf[p+1] = byte(value), byte(value>>8)
+	case BlockPtr:
+		b.ptr = (value &^ 1) % BlockLen
+	}
+}
+
+func (b *Block) command(cmd uint16) uint16 {
+	off := int64(b.num) * BlockLen
+	switch cmd {
+	case BlockRead:
+		n, err := b.storage.ReadAt(b.buf[:], off)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return BlockFailed
+		}
+		// blocks past end of storage read as zeros
+		for i := n; i < BlockLen; i++ {
+			b.buf[i] = 0
+		}
+	case BlockWrite:
+		if _, err := b.storage.WriteAt(b.buf[:], off); err != nil {
+			return BlockFailed
+		}
+	default:
+		return BlockBadCmd
+	}
+	return BlockOK
+}
@@ -0,0 +1,73 @@
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dim13/j1"
+)
+
+func TestBlock(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "blocks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b := NewBlock(f)
+
+	b.Write(BlockNum, 2)
+	b.Write(BlockPtr, 0x3ff)
+	if v := b.Read(BlockPtr); v != 0x3fe {
+		t.Errorf("ptr: got %x, want 3fe", v)
+	}
+	b.Write(BlockData, 0x4241)
+	if v := b.Read(BlockPtr); v != 0 {
+		t.Errorf("ptr: got %x, want 0", v)
+	}
+	b.Write(BlockCmd, BlockWrite)
+	if v := b.Read(BlockCmd); v != BlockOK {
+		t.Errorf("write: got %v, want %v", v, BlockOK)
+	}
+	if st, _ := f.Stat(); st.Size() != 3*BlockLen {
+		t.Errorf("size: got %v, want %v", st.Size(), 3*BlockLen)
+	}
+
+	b.Write(BlockNum, 5)
+	b.Write(BlockCmd, BlockRead)
+	if v := b.Read(BlockData); v != 0 || b.Read(BlockCmd) != BlockOK {
+		t.Errorf("read past end: got %x, status %v", v, b.Read(BlockCmd))
+	}
+	b.Write(BlockNum, 2)
+	b.Write(BlockCmd, BlockRead)
+	b.Write(BlockPtr, 0x3fe)
+	if v := b.Read(BlockData); v != 0x4241 {
+		t.Errorf("read: got %x, want 4241", v)
+	}
+	b.Write(BlockCmd, 7)
+	if v := b.Read(BlockCmd); v != BlockBadCmd {
+		t.Errorf("got %v, want %v", v, BlockBadCmd)
+	}
+}
+
+func TestBlockWords(t *testing.T) {
+	glue, err := os.ReadFile("block.fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(t.TempDir(), "blocks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	attach := func(vm *j1.Core) {
+		vm.Attach(0x7020, BlockSize, NewBlock(f))
+	}
+
+	eval(t, string(glue)+"1234 3 block ! update 5678 4 buffer cell+ ! update flush\n", attach)
+	out := eval(t, string(glue)+"3 block @ . 4 block cell+ @ . 3 block @ .\n", attach)
+	if !strings.Contains(out, "1234 5678 1234 ok") {
+		t.Errorf("got %q", out)
+	}
+}
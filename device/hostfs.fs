@@ -0,0 +1,37 @@
+( include host files through HostFS device, see hostfs.go )
+( usage: include file.fs )
+hex
+7010 constant fcmd
+7012 constant fname
+7014 constant fdata
+7016 constant fline
+7018 constant fdepth
+100 constant #fbuf
+create fbufs 8 #fbuf * allot
+: fbuf ( -- a ) fdepth @ 1- #fbuf * fbufs + ;
+: fopen ( b u -- )
+   for aft count fname ! then next drop
+   1 fcmd ! fcmd @ abort" can't open" ;
+: fgetline ( -- b u t | f )
+   fbuf 0
+   begin fdata @ dup ffff <> while
+      dup a = if drop -1 exit then
+      over #fbuf u< if >r 2dup + r> swap c! 1+ else drop then
+   repeat drop
+   dup if -1 exit then 2drop 0 ;
+: fevaluate ( b u -- )
+   >in @ >r #tib @ >r tib @ >r
+   0 >in ! #tib ! tib !
+   begin token dup c@ while 'eval @execute repeat drop
+   r> tib ! r> #tib ! r> >in ! ;
+: include ( -- ; <string> )
+   token count fopen
+   begin fgetline while fevaluate repeat
+   2 fcmd ! ;
+'abort @ constant fabort
+: fabort1 ( -- )
+   fdepth @ if
+      base @ >r decimal ." line" fline @ . cr r> base !
+      3 fcmd !
+   then fabort execute ;
+' fabort1 'abort !
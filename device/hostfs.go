@@ -0,0 +1,200 @@
+// Package device provides memory-mapped peripherals for J1 core
+package device
+
+import (
+	"bufio"
+	_ "embed"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HostFSWords is eForth source of include over HostFS at 0x7010
+//
+//go:embed hostfs.fs
+var HostFSWords string
+
+// HostFS registers, relative to base address
+//
+//	offset  read                     write
+//	0       status of last command   command
+//	2       -                        append character to file name
+//	4       next byte, EOF at end    -
+//	6       line in current file     -
+//	8       number of open files     -
+const (
+	FileCmd   = 0
+	FileName  = 2
+	FileData  = 4
+	FileLine  = 6
+	FileDepth = 8
+	FileSize  = 10 // size of register window
+)
+
+// HostFS commands
+const (
+	FileOpen     = 1 // open file by collected name, name is reset
+	FileClose    = 2 // close current file
+	FileCloseAll = 3 // close all files
+)
+
+// HostFS status
+const (
+	FileOK       = 0
+	FileNotFound = 1
+	FileDenied   = 2
+	FileNotOpen  = 3
+	FileBadCmd   = 4
+)
+
+// EOF is read from data register at end of file
+const EOF = 0xffff
+
+// maxDepth limits nested includes
+const maxDepth = 8
+
+type hostFile struct {
+	f    *os.File
+	r    *bufio.Reader
+	line uint16
+	nl   bool // line is advanced on first byte after newline
+}
+
+// HostFS gives read access to host files below root directory.
+// Opened files are stacked, reads go to the most recently opened one.
+type HostFS struct {
+	root   string
+	name   strings.Builder
+	files  []*hostFile
+	status uint16
+}
+
+// NewHostFS sandboxed to root directory
+func NewHostFS(root string) *HostFS {
+	return &HostFS{root: root}
+}
+
+// Read register
+func (h *HostFS) Read(addr uint16) uint16 {
+	switch addr {
+	case FileCmd:
+		return h.status
+	case FileData:
+		return h.next()
+	case FileLine:
+		if f := h.current(); f != nil {
+			return f.line
+		}
+	case FileDepth:
+		return uint16(len(h.files))
+	}
+	return 0
+}
+
+// Write register
+func (h *HostFS) Write(addr, value uint16) {
+	switch addr {
+	case FileCmd:
+		h.status = h.command(value)
+	case FileName:
+		h.name.WriteByte(byte(value))
+	}
+}
+
+func (h *HostFS) command(cmd uint16) uint16 {
+	switch cmd {
+	case FileOpen:
+		defer h.name.Reset()
+		return h.open(h.name.String())
+	case FileClose:
+		if len(h.files) == 0 {
+			return FileNotOpen
+		}
+		h.pop()
+	case FileCloseAll:
+		h.Close()
+	default:
+		return FileBadCmd
+	}
+	return FileOK
+}
+
+// resolve name inside of root, symlinks may not escape it either
+func (h *HostFS) resolve(name string) (string, bool) {
+	if !filepath.IsLocal(name) {
+		return "", false
+	}
+	root, err := filepath.EvalSymlinks(h.root)
+	if err != nil {
+		return "", false
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(root, name))
+	if err != nil {
+		// let open report missing file
+		return filepath.Join(root, name), true
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return path, true
+}
+
+func (h *HostFS) open(name string) uint16 {
+	if len(h.files) >= maxDepth {
+		return FileDenied
+	}
+	path, ok := h.resolve(name)
+	if !ok {
+		return FileDenied
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return FileNotFound
+		}
+		return FileDenied
+	}
+	if st, err := f.Stat(); err != nil || st.IsDir() {
+		f.Close()
+		return FileDenied
+	}
+	h.files = append(h.files, &hostFile{f: f, r: bufio.NewReader(f), line: 1})
+	return FileOK
+}
+
+func (h *HostFS) current() *hostFile {
+	if len(h.files) == 0 {
+		return nil
+	}
+	return h.files[len(h.files)-1]
+}
+
+func (h *HostFS) pop() {
+	h.current().f.Close()
+	h.files = h.files[:len(h.files)-1]
+}
+
+func (h *HostFS) next() uint16 {
+	f := h.current()
+	if f == nil {
+		return EOF
+	}
+	b, err := f.r.ReadByte()
+	if err != nil {
+		return EOF
+	}
+	if f.nl {
+		f.line++
+	}
+	f.nl = b == '\n'
+	return uint16(b)
+}
+
+// Close all open files
+func (h *HostFS) Close() error {
+	for len(h.files) > 0 {
+		h.pop()
+	}
+	return nil
+}
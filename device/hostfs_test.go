@@ -0,0 +1,173 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dim13/j1"
+	"github.com/dim13/j1/eforth"
+)
+
+// feeder console stops core once input is consumed and eForth waits for more
+type feeder struct {
+	input  []byte
+	output bytes.Buffer
+	idle   int
+	cancel func()
+}
+
+func (f *feeder) Read() uint16 {
+	v := f.input[0]
+	f.input = f.input[1:]
+	return uint16(v)
+}
+
+func (f *feeder) Write(v uint16) {
+	f.idle = 0
+	f.output.WriteByte(byte(v))
+}
+
+func (f *feeder) Len() uint16 {
+	if len(f.input) > 0 {
+		return 1
+	}
+	if f.idle++; f.idle > 1 {
+		f.cancel()
+	}
+	return 0
+}
+
+func (f *feeder) Stop() { f.cancel() }
+
+// eval input on eForth with devices attached and return its output
+func eval(t *testing.T, input string, attach func(*j1.Core)) string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	con := &feeder{input: []byte(input), cancel: cancel}
+	vm := j1.New(con)
+	if _, err := vm.Write(eforth.Image); err != nil {
+		t.Fatal(err)
+	}
+	attach(vm)
+	vm.Run(ctx)
+	return con.output.String()
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHostFS(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.fs": "ab\ncd",
+		"b.fs": "x",
+	})
+	h := NewHostFS(dir)
+	defer h.Close()
+
+	open := func(name string) uint16 {
+		for _, c := range name {
+			h.Write(FileName, uint16(c))
+		}
+		h.Write(FileCmd, FileOpen)
+		return h.Read(FileCmd)
+	}
+	read := func() (s string) {
+		for v := h.Read(FileData); v != EOF; v = h.Read(FileData) {
+			s += string(rune(v))
+		}
+		return s
+	}
+
+	if v := open("a.fs"); v != FileOK {
+		t.Fatalf("open: got %v, want %v", v, FileOK)
+	}
+	h.Read(FileData)
+	if v := open("b.fs"); v != FileOK {
+		t.Fatalf("open: got %v, want %v", v, FileOK)
+	}
+	if v := h.Read(FileDepth); v != 2 {
+		t.Errorf("depth: got %v, want 2", v)
+	}
+	if s := read(); s != "x" {
+		t.Errorf("got %q, want %q", s, "x")
+	}
+	h.Write(FileCmd, FileClose)
+	if s := read(); s != "b\ncd" {
+		t.Errorf("got %q, want %q", s, "b\ncd")
+	}
+	if v := h.Read(FileLine); v != 2 {
+		t.Errorf("line: got %v, want 2", v)
+	}
+	h.Write(FileCmd, FileCloseAll)
+	h.Write(FileCmd, FileClose)
+	if v := h.Read(FileCmd); v != FileNotOpen {
+		t.Errorf("close: got %v, want %v", v, FileNotOpen)
+	}
+
+	for _, name := range []string{"../a.fs", "/etc/passwd", "."} {
+		if v := open(name); v != FileDenied {
+			t.Errorf("open %v: got %v, want %v", name, v, FileDenied)
+		}
+	}
+	if v := open("c.fs"); v != FileNotFound {
+		t.Errorf("open: got %v, want %v", v, FileNotFound)
+	}
+	outside := t.TempDir()
+	writeFiles(t, outside, map[string]string{"secret": "x"})
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dir, "link")); err != nil {
+		t.Skip(err)
+	}
+	if v := open("link"); v != FileDenied {
+		t.Errorf("open link: got %v, want %v", v, FileDenied)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	glue, err := os.ReadFile("hostfs.fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"lib.fs":  "( library )\n: sq ( n -- n )\n   dup * ;\n",
+		"main.fs": "include lib.fs\n\\ comment\n: sq3 3 sq ;\n",
+		"bad.fs":  "1 2\n3 nosuch\n4\n",
+	})
+	attach := func(vm *j1.Core) {
+		vm.Attach(0x7010, FileSize, NewHostFS(dir))
+	}
+
+	out := eval(t, string(glue)+"include main.fs\nsq3 .\n", attach)
+	if !strings.Contains(out, "sq3 . 9 ok") {
+		t.Errorf("got %q", out)
+	}
+	out = eval(t, string(glue)+"include bad.fs\n", attach)
+	if !strings.Contains(out, "nosuch?\r\nline 2") {
+		t.Errorf("got %q", out)
+	}
+	boot := func(vm *j1.Core) {
+		attach(vm)
+		if err := vm.Boot([]byte(HostFSWords)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out = eval(t, "include main.fs\nsq3 .\n", boot)
+	if !strings.Contains(out, "sq3 . 9 ok") {
+		t.Errorf("booted: got %q", out)
+	}
+	out = eval(t, string(glue)+"include none.fs\n", attach)
+	if !strings.Contains(out, "can't open?") {
+		t.Errorf("got %q", out)
+	}
+}
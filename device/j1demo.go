@@ -0,0 +1,169 @@
+package device
+
+// Peripherals of docs/j1demo/verilog/top.v, relative to their base addresses
+
+// Clock registers of 32-bit microsecond counter, at 0x6000 on j1demo
+//
+//	offset  read
+//	0       microseconds, low word
+//	2       microseconds, high word
+//
+// Words are read as they are, firmware reads twice until both agree.
+const (
+	ClockLow  = 0
+	ClockHigh = 2
+	ClockSize = 4 // size of register window
+
+	ClockDivider = 33 // cycles per microsecond, at 33 MHz system clock
+)
+
+// Clock counts microseconds of cycles reported by a function, typically
+// Core.Cycles, so time runs as on the board whatever the host speed
+type Clock struct {
+	cycles func() uint64
+}
+
+// NewClock counting cycles reported by fn
+func NewClock(fn func() uint64) *Clock {
+	return &Clock{cycles: fn}
+}
+
+// Read register
+func (c *Clock) Read(addr uint16) uint16 {
+	v := uint32(c.cycles() / ClockDivider)
+	switch addr {
+	case ClockLow:
+		return uint16(v)
+	case ClockHigh:
+		return uint16(v >> 16)
+	}
+	return 0
+}
+
+// Write register, ignored
+func (c *Clock) Write(addr, value uint16) {}
+
+// Multiplier registers, at 0x6100 on j1demo
+//
+//	offset  read                   write
+//	0       -                      factor a
+//	2       -                      factor b
+//	4       product, low word      -
+//	6       product, high word     -
+const (
+	MultA          = 0
+	MultB          = 2
+	MultP          = 4
+	MultiplierSize = 8 // size of register window
+)
+
+// Multiplier gives unsigned 32-bit product of two 16-bit factors
+type Multiplier struct {
+	a, b uint16
+}
+
+// Read register
+func (m *Multiplier) Read(addr uint16) uint16 {
+	p := uint32(m.a) * uint32(m.b)
+	switch addr {
+	case MultP:
+		return uint16(p)
+	case MultP + 2:
+		return uint16(p >> 16)
+	}
+	return 0
+}
+
+// Write register
+func (m *Multiplier) Write(addr, value uint16) {
+	switch addr {
+	case MultA:
+		m.a = value
+	case MultB:
+		m.b = value
+	}
+}
+
+// Switch registers of push buttons SW2 and SW3, at 0x4500 on j1demo
+//
+//	offset  read
+//	0       SW2, 0 while pressed
+//	2       SW3, 0 while pressed
+const (
+	SW2          = 0
+	SW3          = 2
+	SwitchesSize = 4 // size of register window
+)
+
+// Switches are push buttons, released at start
+type Switches struct {
+	pressed [2]bool
+}
+
+// Press button at register addr, or release it
+func (s *Switches) Press(addr uint16, down bool) {
+	if k := addr >> 1; k < 2 {
+		s.pressed[k] = down
+	}
+}
+
+// Read register
+func (s *Switches) Read(addr uint16) uint16 {
+	if k := addr >> 1; k < 2 && !s.pressed[k] {
+		return 1
+	}
+	return 0
+}
+
+// Write register, ignored
+func (s *Switches) Write(addr, value uint16) {}
+
+// Serial is transmit line of RS232 port, at 0x5000 on j1demo. Firmware
+// writes one bit per frame bit, low bit of value, and times them by busy
+// loops. Frames of start bit, eight data bits, least significant first,
+// and stop bit are decoded into bytes.
+type Serial struct {
+	emit func(uint16)
+	bits int // received, 0 while line is idle
+	data uint16
+}
+
+// NewSerial passing received bytes to fn, typically Console.Write
+func NewSerial(fn func(uint16)) *Serial {
+	return &Serial{emit: fn}
+}
+
+// Read register
+func (s *Serial) Read(addr uint16) uint16 {
+	return 0
+}
+
+// Write bit of frame
+func (s *Serial) Write(addr, value uint16) {
+	bit := value & 1
+	switch {
+	case s.bits == 0: // idle, wait for start bit
+		if bit == 0 {
+			s.bits, s.data = 1, 0
+		}
+	case s.bits <= 8:
+		s.data |= bit << (s.bits - 1)
+		s.bits++
+	default: // stop bit, frame is dropped if it is missing
+		if bit == 1 {
+			s.emit(s.data)
+		}
+		s.bits = 0
+	}
+}
+
+// Floating bus, value read from ports nothing drives
+type Floating uint16
+
+// Read register
+func (f Floating) Read(addr uint16) uint16 {
+	return uint16(f)
+}
+
+// Write register, ignored
+func (f Floating) Write(addr, value uint16) {}
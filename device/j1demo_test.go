@@ -0,0 +1,45 @@
+package device
+
+import "testing"
+
+func TestClock(t *testing.T) {
+	cycles := uint64(0x12345 * ClockDivider)
+	c := NewClock(func() uint64 { return cycles })
+	if lo, hi := c.Read(ClockLow), c.Read(ClockHigh); lo != 0x2345 || hi != 1 {
+		t.Errorf("got %x %x", hi, lo)
+	}
+}
+
+func TestMultiplier(t *testing.T) {
+	m := new(Multiplier)
+	m.Write(MultA, 0xffff)
+	m.Write(MultB, 0x1234)
+	if lo, hi := m.Read(MultP), m.Read(MultP+2); lo != 0xedcc || hi != 0x1233 {
+		t.Errorf("got %x %x", hi, lo)
+	}
+}
+
+func TestSwitches(t *testing.T) {
+	s := new(Switches)
+	s.Press(SW3, true)
+	if v2, v3 := s.Read(SW2), s.Read(SW3); v2 != 1 || v3 != 0 {
+		t.Errorf("got %v %v, want 1 0", v2, v3)
+	}
+}
+
+func TestSerial(t *testing.T) {
+	var got []uint16
+	s := NewSerial(func(v uint16) { got = append(got, v) })
+	frame := func(b uint16, stop uint16) {
+		v := (b|stop<<8)<<1 | 0x400 // start, data, stop, idle
+		for ; v != 0; v >>= 1 {
+			s.Write(0, v)
+		}
+	}
+	frame('A', 1)
+	frame('x', 0) // framing error
+	frame('B', 1)
+	if len(got) != 2 || got[0] != 'A' || got[1] != 'B' {
+		t.Errorf("got %q", got)
+	}
+}
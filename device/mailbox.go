@@ -0,0 +1,45 @@
+package device
+
+// Mailbox registers, relative to base address
+//
+//	offset  read                       write
+//	0       next message, 0 if none    send message, dropped if full
+//	2       messages waiting           -
+const (
+	MailboxData  = 0
+	MailboxCount = 2
+	MailboxSize  = 4 // size of register window
+)
+
+// Mailbox is a queue of messages between cores of j1.System
+type Mailbox struct {
+	queue []uint16
+	depth int
+}
+
+// NewMailbox holding depth messages at most
+func NewMailbox(depth int) *Mailbox {
+	return &Mailbox{depth: depth}
+}
+
+// Read register
+func (m *Mailbox) Read(addr uint16) uint16 {
+	switch addr {
+	case MailboxData:
+		if len(m.queue) > 0 {
+			v := m.queue[0]
+			m.queue = m.queue[1:]
+			return v
+		}
+	case MailboxCount:
+		return uint16(len(m.queue))
+	}
+	return 0
+}
+
+// Write register
+func (m *Mailbox) Write(addr, value uint16) {
+	if addr == MailboxData && len(m.queue) < m.depth {
+		m.queue = append(m.queue, value)
+	}
+}
@@ -0,0 +1,21 @@
+package device
+
+import "testing"
+
+func TestMailbox(t *testing.T) {
+	m := NewMailbox(2)
+	if v := m.Read(MailboxData); v != 0 {
+		t.Errorf("empty: got %v, want 0", v)
+	}
+	for _, v := range []uint16{1, 2, 3} {
+		m.Write(MailboxData, v)
+	}
+	if v := m.Read(MailboxCount); v != 2 {
+		t.Errorf("count: got %v, want 2", v)
+	}
+	for _, want := range []uint16{1, 2, 0} {
+		if v := m.Read(MailboxData); v != want {
+			t.Errorf("got %v, want %v", v, want)
+		}
+	}
+}
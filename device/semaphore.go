@@ -0,0 +1,40 @@
+package device
+
+// Semaphore registers, relative to base address, one per counter
+//
+//	offset  read                                write
+//	2×k     -1 if counter k taken, 0 if zero   add value to counter k
+//
+// Reading takes one from a non-zero counter, so a core owns the resource
+// if it reads -1.
+const (
+	Semaphores    = 8 // counters
+	SemaphoreSize = 2 * Semaphores
+)
+
+// Semaphore is a set of counting semaphores for cores of j1.System
+type Semaphore struct {
+	count [Semaphores]uint16
+}
+
+// NewSemaphore with all counters zero
+func NewSemaphore() *Semaphore {
+	return &Semaphore{}
+}
+
+// Read register, trying to take counter
+func (s *Semaphore) Read(addr uint16) uint16 {
+	k := addr >> 1
+	if k >= Semaphores || s.count[k] == 0 {
+		return 0
+	}
+	s.count[k]--
+	return 0xffff
+}
+
+// Write register, releasing counter
+func (s *Semaphore) Write(addr, value uint16) {
+	if k := addr >> 1; k < Semaphores {
+		s.count[k] += value
+	}
+}
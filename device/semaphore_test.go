@@ -0,0 +1,22 @@
+package device
+
+import "testing"
+
+func TestSemaphore(t *testing.T) {
+	s := NewSemaphore()
+	if v := s.Read(2); v != 0 {
+		t.Errorf("zero: got %x, want 0", v)
+	}
+	s.Write(2, 2)
+	for _, want := range []uint16{0xffff, 0xffff, 0} {
+		if v := s.Read(2); v != want {
+			t.Errorf("got %x, want %x", v, want)
+		}
+	}
+	if v := s.Read(0); v != 0 {
+		t.Errorf("other counter: got %x, want 0", v)
+	}
+	if v := s.Read(SemaphoreSize); v != 0 {
+		t.Errorf("out of range: got %x, want 0", v)
+	}
+}
@@ -0,0 +1,8 @@
+( time through Timer device, see timer.go )
+hex
+7030 constant tcycles
+7034 constant tmillis
+7038 constant tcount
+703a constant tstatus
+: ticks ( -- u ) tmillis @ ;
+: ms ( u -- ) tcount ! begin tstatus @ until 0 tstatus ! ;
@@ -0,0 +1,94 @@
+package device
+
+import "time"
+
+// Timer registers, relative to base address
+//
+//	offset  read                       write
+//	0       cycles, low word           -
+//	2       cycles, high word          -
+//	4       milliseconds, low word     -
+//	6       milliseconds, high word    -
+//	8       countdown, ms remaining    start countdown, ms
+//	10      countdown expired flag     clear flag
+//
+// Reading the low word of a counter latches its high word, each counter
+// has its own latch.
+const (
+	TimerCycles    = 0
+	TimerMillis    = 4
+	TimerCountdown = 8
+	TimerStatus    = 10
+	TimerSize      = 12 // size of register window
+)
+
+// Timer provides free-running cycle and millisecond counters and
+// a programmable millisecond countdown
+type Timer struct {
+	cycles   func() uint64
+	now      func() time.Time
+	start    time.Time
+	deadline time.Time
+	running  bool
+	expired  bool
+	latch    [2]uint16 // high words of cycles and milliseconds
+}
+
+// NewTimer counting cycles reported by fn, typically Core.Cycles
+func NewTimer(fn func() uint64) *Timer {
+	t := &Timer{cycles: fn, now: time.Now}
+	t.start = t.now()
+	return t
+}
+
+func (t *Timer) millis() uint32 {
+	return uint32(t.now().Sub(t.start).Milliseconds())
+}
+
+func (t *Timer) remaining() uint16 {
+	if !t.running {
+		return 0
+	}
+	d := t.deadline.Sub(t.now())
+	if d <= 0 {
+		t.running, t.expired = false, true
+		return 0
+	}
+	return uint16((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// Read register
+func (t *Timer) Read(addr uint16) uint16 {
+	switch addr {
+	case TimerCycles:
+		v := uint32(t.cycles())
+		t.latch[0] = uint16(v >> 16)
+		return uint16(v)
+	case TimerMillis:
+		v := t.millis()
+		t.latch[1] = uint16(v >> 16)
+		return uint16(v)
+	case TimerCycles + 2:
+		return t.latch[0]
+	case TimerMillis + 2:
+		return t.latch[1]
+	case TimerCountdown:
+		return t.remaining()
+	case TimerStatus:
+		if t.remaining(); t.expired {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Write register
+func (t *Timer) Write(addr, value uint16) {
+	switch addr {
+	case TimerCountdown:
+		t.deadline = t.now().Add(time.Duration(value) * time.Millisecond)
+		t.running, t.expired = true, false
+	case TimerStatus:
+		t.expired = false
+	}
+}
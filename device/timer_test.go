@@ -0,0 +1,73 @@
+package device
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dim13/j1"
+)
+
+func TestTimer(t *testing.T) {
+	now := time.Unix(0, 0)
+	cycles := uint64(0x12345678)
+	tm := NewTimer(func() uint64 { return cycles })
+	tm.now = func() time.Time { return now }
+	tm.start = now
+
+	if lo, hi := tm.Read(TimerCycles), tm.Read(TimerCycles+2); lo != 0x5678 || hi != 0x1234 {
+		t.Errorf("cycles: got %x %x", hi, lo)
+	}
+	now = now.Add(0x10002 * time.Millisecond)
+	if lo, hi := tm.Read(TimerMillis), tm.Read(TimerMillis+2); lo != 2 || hi != 1 {
+		t.Errorf("millis: got %x %x", hi, lo)
+	}
+
+	// counters latch independently
+	tm.Read(TimerMillis)
+	if lo, hi := tm.Read(TimerCycles), tm.Read(TimerMillis+2); lo != 0x5678 || hi != 1 {
+		t.Errorf("interleaved: got cycles %x, millis high %x", lo, hi)
+	}
+
+	if v := tm.Read(TimerStatus); v != 0 {
+		t.Errorf("status: got %v, want 0", v)
+	}
+	tm.Write(TimerCountdown, 10)
+	now = now.Add(3 * time.Millisecond)
+	if v := tm.Read(TimerCountdown); v != 7 {
+		t.Errorf("countdown: got %v, want 7", v)
+	}
+	if v := tm.Read(TimerStatus); v != 0 {
+		t.Errorf("status: got %v, want 0", v)
+	}
+	now = now.Add(7 * time.Millisecond)
+	if v := tm.Read(TimerStatus); v != 1 {
+		t.Errorf("status: got %v, want 1", v)
+	}
+	if v := tm.Read(TimerCountdown); v != 0 {
+		t.Errorf("countdown: got %v, want 0", v)
+	}
+	tm.Write(TimerStatus, 0)
+	if v := tm.Read(TimerStatus); v != 0 {
+		t.Errorf("status: got %v, want 0", v)
+	}
+}
+
+func TestTimerWords(t *testing.T) {
+	glue, err := os.ReadFile("timer.fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	attach := func(vm *j1.Core) {
+		vm.Attach(0x7030, TimerSize, NewTimer(vm.Cycles))
+	}
+	start := time.Now()
+	out := eval(t, string(glue)+"decimal ticks 20 ms ticks swap - 20 < . tcycles @ 0= .\n", attach)
+	if !strings.Contains(out, " 0 0 ok") {
+		t.Errorf("got %q", out)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("ms returned after %v", d)
+	}
+}
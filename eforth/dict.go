@@ -0,0 +1,218 @@
+package eforth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Memory layout of eForth 1.04, see docs/j1eforth/j1.4th
+const (
+	Up       = 0x3e80       // user area
+	Wordlist = Up + 0x22    // forth-wordlist, head of dictionary at run time
+	DP       = Up + 0x2c    // dp, end of dictionary at run time
+	uzero    = 0x0002       // initial values of user area in image
+	initDP   = uzero + 0x2c // dp, as initialised in image
+	initLast = uzero + 0x2e // last, as initialised in image
+	lenMask  = 0x1f         // name length in count byte
+)
+
+// Flags of word header, kept in count byte
+type Flags uint8
+
+// Known flags
+const (
+	CompileOnly Flags = 0x40
+	Immediate   Flags = 0x80
+)
+
+func (f Flags) String() string {
+	var s []string
+	if f&Immediate != 0 {
+		s = append(s, "immediate")
+	}
+	if f&CompileOnly != 0 {
+		s = append(s, "compile-only")
+	}
+	return strings.Join(s, " ")
+}
+
+// Word of eForth dictionary
+//
+//	link  cell, name address of previous word
+//	name  count byte with flags, characters, aligned
+//	code  cells up to next word
+type Word struct {
+	Name  string
+	Addr  uint16 // code field address, in bytes
+	Flags Flags
+	Size  uint16 // code size, in bytes
+}
+
+func (w Word) String() string {
+	return fmt.Sprintf("%0.4X %v", w.Addr, w.Name)
+}
+
+// ErrBadLink is reported for dictionary links pointing out of memory or
+// forming a loop
+var ErrBadLink = errors.New("eforth: bad dictionary link")
+
+func byteAt(mem []uint16, addr uint16) uint8 {
+	return uint8(mem[addr>>1] >> (8 * (addr & 1)))
+}
+
+func header(mem []uint16, na uint16) (Word, error) {
+	if int(na>>1) >= len(mem) || na < 2 {
+		return Word{}, ErrBadLink
+	}
+	count := byteAt(mem, na)
+	size := uint16(count & lenMask)
+	end := na + 1 + size
+	if int(end>>1) >= len(mem) {
+		return Word{}, ErrBadLink
+	}
+	name := make([]byte, size)
+	for i := range name {
+		name[i] = byteAt(mem, na+1+uint16(i))
+	}
+	return Word{
+		Name:  string(name),
+		Addr:  end + end&1,
+		Flags: Flags(count &^ lenMask),
+	}, nil
+}
+
+// heads returns first name address and end of dictionary. Memory of
+// a running system has them in user area, a fresh image in its initial
+// values. Memory ending within user area is rejected.
+func heads(mem []uint16) (uint16, uint16, error) {
+	switch {
+	case len(mem) <= initLast>>1:
+		return 0, 0, ErrBadLink
+	case len(mem) <= Up>>1:
+		return mem[initLast>>1], mem[initDP>>1], nil
+	case len(mem) <= DP>>1:
+		return 0, 0, ErrBadLink
+	}
+	if na := mem[Wordlist>>1]; na != 0 {
+		return na, mem[DP>>1], nil
+	}
+	return mem[initLast>>1], mem[initDP>>1], nil
+}
+
+// Words of dictionary in memory cells, most recent first
+func Words(mem []uint16) ([]Word, error) {
+	na, dp, err := heads(mem)
+	if err != nil {
+		return nil, err
+	}
+	var words []Word
+	var names []uint16
+	for na != 0 {
+		if len(words) > len(mem) {
+			return nil, ErrBadLink
+		}
+		w, err := header(mem, na)
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, w)
+		names = append(names, na)
+		na = mem[(na-2)>>1]
+	}
+	// code ends where the link field of next word in memory starts
+	order := make([]int, len(words))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return words[order[i]].Addr < words[order[j]].Addr })
+	for k, i := range order {
+		end := dp
+		if k+1 < len(order) {
+			end = names[order[k+1]] - 2
+		}
+		if end > words[i].Addr {
+			words[i].Size = end - words[i].Addr
+		}
+	}
+	return words, nil
+}
+
+// Dictionary for symbolisation
+type Dictionary struct {
+	words  []Word
+	byName map[string]int
+	byAddr []int // sorted by address
+}
+
+// NewDictionary of memory cells
+func NewDictionary(mem []uint16) (*Dictionary, error) {
+	words, err := Words(mem)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dictionary{
+		words:  words,
+		byName: make(map[string]int),
+		byAddr: make([]int, len(words)),
+	}
+	for i := len(words) - 1; i >= 0; i-- {
+		d.byName[words[i].Name] = i // recent definition wins
+		d.byAddr[i] = i
+	}
+	sort.Slice(d.byAddr, func(i, j int) bool { return words[d.byAddr[i]].Addr < words[d.byAddr[j]].Addr })
+	return d, nil
+}
+
+// Words of dictionary, most recent first
+func (d *Dictionary) Words() []Word {
+	return d.words
+}
+
+// Lookup word by name
+func (d *Dictionary) Lookup(name string) (Word, bool) {
+	i, ok := d.byName[name]
+	if !ok {
+		return Word{}, false
+	}
+	return d.words[i], true
+}
+
+// At returns word with code field at byte address addr
+func (d *Dictionary) At(addr uint16) (Word, bool) {
+	w, ok := d.Within(addr)
+	return w, ok && w.Addr == addr
+}
+
+// Within returns word which code contains byte address addr
+func (d *Dictionary) Within(addr uint16) (Word, bool) {
+	k := sort.Search(len(d.byAddr), func(k int) bool { return d.words[d.byAddr[k]].Addr > addr })
+	if k == 0 {
+		return Word{}, false
+	}
+	w := d.words[d.byAddr[k-1]]
+	if addr != w.Addr && addr-w.Addr >= w.Size {
+		return Word{}, false
+	}
+	return w, true
+}
+
+// Lookup code field address of word by name in memory cells
+func Lookup(mem []uint16, name string) (uint16, bool) {
+	na, _, err := heads(mem)
+	if err != nil {
+		return 0, false
+	}
+	for n := 0; na != 0 && n < len(mem); n++ {
+		w, err := header(mem, na)
+		if err != nil {
+			return 0, false
+		}
+		if w.Name == name {
+			return w.Addr, true
+		}
+		na = mem[(na-2)>>1]
+	}
+	return 0, false
+}
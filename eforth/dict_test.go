@@ -0,0 +1,98 @@
+package eforth
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func cells(b []byte) []uint16 {
+	mem := make([]uint16, len(b)/2)
+	for i := range mem {
+		mem[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return mem
+}
+
+func TestWords(t *testing.T) {
+	mem := cells(Image)
+	words, err := Words(mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) < 100 {
+		t.Fatalf("got %v words", len(words))
+	}
+	if w := words[0]; w.Name != "cold" {
+		t.Errorf("last word: got %v, want cold", w.Name)
+	}
+	d, err := NewDictionary(mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name  string
+		flags Flags
+	}{
+		{name: "dup"},
+		{name: "words"},
+		{name: "if", flags: Immediate | CompileOnly},
+		{name: "(", flags: Immediate},
+		{name: "exit"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, ok := d.Lookup(tc.name)
+			if !ok {
+				t.Fatal("not found")
+			}
+			if w.Flags != tc.flags {
+				t.Errorf("flags: got %v, want %v", w.Flags, tc.flags)
+			}
+			if w.Size == 0 {
+				t.Error("zero size")
+			}
+			if ca, _ := Lookup(mem, tc.name); ca != w.Addr {
+				t.Errorf("Lookup: got %0.4X, want %0.4X", ca, w.Addr)
+			}
+			if got, ok := d.At(w.Addr); !ok || got != w {
+				t.Errorf("At: got %v", got)
+			}
+			if got, ok := d.Within(w.Addr + w.Size - 1); !ok || got != w {
+				t.Errorf("Within: got %v", got)
+			}
+		})
+	}
+	if _, ok := Lookup(mem, "nosuch"); ok {
+		t.Error("nosuch found")
+	}
+}
+
+func TestWordsLoop(t *testing.T) {
+	mem := make([]uint16, 0x40)
+	mem[initLast>>1] = 0x22 // name at 0x22, link at 0x20 points to itself
+	mem[0x20>>1] = 0x22
+	mem[0x22>>1] = 1 | 'a'<<8
+	if _, err := Words(mem); !errors.Is(err, ErrBadLink) {
+		t.Errorf("got %v, want %v", err, ErrBadLink)
+	}
+}
+
+func TestWordsShort(t *testing.T) {
+	// memory ending within user area, with run time head set
+	for _, n := range []int{Up>>1 + 1, Wordlist>>1 + 1, DP >> 1} {
+		mem := make([]uint16, n)
+		if int(Wordlist>>1) < n {
+			mem[Wordlist>>1] = 0x22
+		}
+		if _, err := Words(mem); !errors.Is(err, ErrBadLink) {
+			t.Errorf("%#x cells: got %v, want %v", n, err, ErrBadLink)
+		}
+		if _, err := NewDictionary(mem); !errors.Is(err, ErrBadLink) {
+			t.Errorf("%#x cells: got %v, want %v", n, err, ErrBadLink)
+		}
+		if _, ok := Lookup(mem, "a"); ok {
+			t.Errorf("%#x cells: found", n)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+// Package eforth provides eForth 1.04 image for J1
+package eforth
+
+import _ "embed"
+
+//go:generate go run gen.go
+
+// Image of eForth 1.04, cross-compiled from docs/j1eforth/j1.4th
+//
+//go:embed j1e.bin
+var Image []byte
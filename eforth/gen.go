@@ -0,0 +1,46 @@
+//go:build ignore
+
+// Gen metacompiles j1e.bin from docs/j1eforth/j1.4th
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/dim13/j1/cross"
+)
+
+func main() {
+	log.SetFlags(0)
+	src, err := filepath.Abs("../docs/j1eforth")
+	if err != nil {
+		log.Fatal(err)
+	}
+	out, err := filepath.Abs("j1e.bin")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// j1.4th saves j1.bin and j1.hex to working directory
+	tmp, err := os.MkdirTemp("", "j1e")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := os.Chdir(tmp); err != nil {
+		log.Fatal(err)
+	}
+	f := cross.NewForth()
+	f.Dir = src
+	if err := f.Include("j1.4th"); !errors.Is(err, cross.ErrBye) {
+		log.Fatalf("j1.4th: %v", err)
+	}
+	image, err := os.ReadFile("j1.bin")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(out, image, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,44 @@
+package j1
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors of loading and executing images, wrapped in Fault
+var (
+	ErrInvalidInstruction = errors.New("invalid instruction")
+	ErrImageTooLarge      = errors.New("image too large")
+	ErrOddLength          = errors.New("odd image length")
+)
+
+// Fault at byte address: of instruction executed, or of image loaded
+type Fault struct {
+	Addr uint16
+	Err  error
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("%0.4X: %v", f.Addr, f.Err)
+}
+
+func (f *Fault) Unwrap() error {
+	return f.Err
+}
+
+// fail stops core at instruction just fetched
+func (c *Core) fail(err error) {
+	c.pc = (c.pc - 1) & pcMask
+	c.cycles--
+	c.fault = &Fault{Addr: c.pc << 1, Err: err}
+	c.io = true
+}
+
+// Err is fault which stopped core, nil if there is none. Execute does
+// nothing and Run returns at once until Reset clears it.
+func (c *Core) Err() error {
+	if c.fault == nil {
+		return nil
+	}
+	return c.fault
+}
@@ -0,0 +1,190 @@
+package j1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// env of watch condition: registers at instruction accessing memory
+type env struct {
+	T, N, R, PC uint16
+	V, Old      uint16 // value read or written, and cell before
+}
+
+// expr is compiled condition, non-zero is true
+type expr func(e *env) uint16
+
+var variables = map[string]func(e *env) uint16{
+	"T":   func(e *env) uint16 { return e.T },
+	"N":   func(e *env) uint16 { return e.N },
+	"R":   func(e *env) uint16 { return e.R },
+	"PC":  func(e *env) uint16 { return e.PC },
+	"V":   func(e *env) uint16 { return e.V },
+	"OLD": func(e *env) uint16 { return e.Old },
+}
+
+// binary operators by precedence, lowest first, as in Go
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<=", ">=", "<", ">"},
+	{"+", "-", "|", "^"},
+	{"&"},
+}
+
+func operator(op string, x, y expr) expr {
+	switch op {
+	case "||":
+		return func(e *env) uint16 { return boolValue(x(e) != 0 || y(e) != 0) }
+	case "&&":
+		return func(e *env) uint16 { return boolValue(x(e) != 0 && y(e) != 0) }
+	case "==":
+		return func(e *env) uint16 { return boolValue(x(e) == y(e)) }
+	case "!=":
+		return func(e *env) uint16 { return boolValue(x(e) != y(e)) }
+	case "<=":
+		return func(e *env) uint16 { return boolValue(x(e) <= y(e)) }
+	case ">=":
+		return func(e *env) uint16 { return boolValue(x(e) >= y(e)) }
+	case "<":
+		return func(e *env) uint16 { return boolValue(x(e) < y(e)) }
+	case ">":
+		return func(e *env) uint16 { return boolValue(x(e) > y(e)) }
+	case "+":
+		return func(e *env) uint16 { return x(e) + y(e) }
+	case "-":
+		return func(e *env) uint16 { return x(e) - y(e) }
+	case "|":
+		return func(e *env) uint16 { return x(e) | y(e) }
+	case "^":
+		return func(e *env) uint16 { return x(e) ^ y(e) }
+	default: // &
+		return func(e *env) uint16 { return x(e) & y(e) }
+	}
+}
+
+// parser of conditions over T, N, R, PC, V and OLD with numbers, Go
+// operators and parentheses. Comparisons are unsigned.
+type parser struct {
+	toks []string
+}
+
+var twoChar = map[string]bool{"||": true, "&&": true, "==": true, "!=": true, "<=": true, ">=": true}
+
+func tokenize(s string) ([]string, error) {
+	var toks []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		n := 1
+		switch r := rune(s[0]); {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			n = strings.IndexFunc(s, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+			if n < 0 {
+				n = len(s)
+			}
+		case len(s) > 1 && twoChar[s[:2]]:
+			n = 2
+		case !strings.ContainsRune("+-|^&<>!()", r):
+			return nil, fmt.Errorf("unexpected %q", r)
+		}
+		toks, s = append(toks, s[:n]), s[n:]
+	}
+	return toks, nil
+}
+
+func (p *parser) next() string {
+	if len(p.toks) == 0 {
+		return ""
+	}
+	return p.toks[0]
+}
+
+func (p *parser) binary(level int) (expr, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.next()
+		found := false
+		for _, v := range precedence[level] {
+			found = found || op == v
+		}
+		if !found {
+			return x, nil
+		}
+		p.toks = p.toks[1:]
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = operator(op, x, y)
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	tok := p.next()
+	if tok == "" {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	p.toks = p.toks[1:]
+	switch tok {
+	case "!", "-", "^":
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		switch tok {
+		case "!":
+			return func(e *env) uint16 { return boolValue(x(e) == 0) }, nil
+		case "-":
+			return func(e *env) uint16 { return -x(e) }, nil
+		}
+		return func(e *env) uint16 { return ^x(e) }, nil
+	case "(":
+		x, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.toks = p.toks[1:]
+		return x, nil
+	}
+	if f, ok := variables[strings.ToUpper(tok)]; ok {
+		return f, nil
+	}
+	v, err := strconv.ParseUint(tok, 0, 16)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+	n := uint16(v)
+	return func(e *env) uint16 { return n }, nil
+}
+
+// compile condition, empty one is always true
+func compile(s string) (expr, error) {
+	if strings.TrimSpace(s) == "" {
+		return func(e *env) uint16 { return 1 }, nil
+	}
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", s, err)
+	}
+	p := &parser{toks: toks}
+	x, err := p.binary(0)
+	if err == nil && p.next() != "" {
+		err = fmt.Errorf("unexpected %q", p.next())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", s, err)
+	}
+	return x, nil
+}
@@ -0,0 +1,85 @@
+package j1
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// canonical tells if v has no bits decoding ignores
+func canonical(isa ISA, v uint16) bool {
+	if !isALU(v) {
+		return true
+	}
+	if isa == J1b {
+		return v&(1<<12) == 0 && (v>>4)&7 <= funcNtoIoAtT
+	}
+	return v&(1<<4) == 0
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, v := range []uint16{0x0000, 0x2000, 0x4000, 0x6000, 0x700c, 0x6d00, 0x8000, 0xffff} {
+		f.Add(v)
+	}
+	f.Fuzz(func(t *testing.T, v uint16) {
+		for _, isa := range []ISA{J1, J1b} {
+			ins := isa.Decode(v)
+			w := isa.Encode(ins)
+			if canonical(isa, v) && w != v {
+				t.Errorf("%v: %0.4X decodes to %v, encodes to %0.4X", isa, v, ins, w)
+			}
+			if again := isa.Decode(w); again != ins {
+				t.Errorf("%v: %0.4X decodes to %v, then to %v", isa, v, ins, again)
+			}
+		}
+	})
+}
+
+// fuzzed core of program and initial state, stacks and st0 come first
+func fuzzed(isa ISA, b []byte) *Core {
+	c := New(&mocConsole{})
+	c.SetISA(isa)
+	var cells [8192]uint16
+	n := len(b) / 2
+	if n > len(cells) {
+		n = len(cells)
+	}
+	for i := 0; i < n; i++ {
+		cells[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	copy(c.d.data[:], cells[0:32])
+	copy(c.r.data[:], cells[32:64])
+	c.st0 = cells[64]
+	c.d.sp, c.r.sp = uint8(cells[65])&0x1f, uint8(cells[66])&0x1f
+	copy(c.memory[:], cells[67:])
+	return c
+}
+
+// steps each fuzzed program runs
+const fuzzSteps = 1000
+
+func FuzzExecute(f *testing.F) {
+	f.Add([]byte{}, false)
+	f.Add(eforthImage(f)[:512], false)
+	f.Add([]byte{0x0c, 0x70, 0x00, 0x40, 0x01, 0x80, 0x00, 0x00}, true)
+	f.Fuzz(func(t *testing.T, b []byte, j1b bool) {
+		isa := J1
+		if j1b {
+			isa = J1b
+		}
+		want := fuzzed(isa, b)
+		for want.cycles < fuzzSteps {
+			want.Execute(want.Fetch())
+			if want.d.sp > 0x1f || want.r.sp > 0x1f || int(want.pc) >= len(want.memory) {
+				t.Fatalf("out of range: pc %0.4X dsp %v rsp %v", want.pc, want.d.sp, want.r.sp)
+			}
+		}
+		got := fuzzed(isa, b)
+		for got.cycles < fuzzSteps {
+			got.run(int(fuzzSteps - got.cycles)) // returns early on i/o
+		}
+		cmp(t, *got, *want)
+		if got.pc != want.pc || got.memory != want.memory {
+			t.Error("run differs from Execute")
+		}
+	})
+}
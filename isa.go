@@ -0,0 +1,116 @@
+package j1
+
+import "fmt"
+
+// ISA variant of J1 instruction set
+type ISA uint8
+
+// Known variants
+const (
+	J1  ISA = iota // classic J1 of j1demo and j1eforth
+	J1b            // revised J1 of docs/j1 with io[T], on 16 bit data path
+)
+
+var isaNames = [...]string{
+	J1:  "j1",
+	J1b: "j1b",
+}
+
+func (isa ISA) String() string {
+	if int(isa) < len(isaNames) {
+		return isaNames[isa]
+	}
+	return fmt.Sprintf("ISA(%d)", uint8(isa))
+}
+
+// ParseISA by name: j1 or j1b
+func ParseISA(s string) (ISA, error) {
+	for i, v := range isaNames {
+		if v == s {
+			return ISA(i), nil
+		}
+	}
+	return J1, fmt.Errorf("unknown ISA %q", s)
+}
+
+// Decode instruction from binary form
+func (isa ISA) Decode(v uint16) Instruction {
+	if isa == J1b && isALU(v) {
+		return newALUj1b(v)
+	}
+	return Decode(v)
+}
+
+// Encode instruction to binary form
+func (isa ISA) Encode(i Instruction) uint16 {
+	if alu, ok := i.(ALU); ok && isa == J1b {
+		return alu.compileJ1b()
+	}
+	return Encode(i)
+}
+
+// J1b ALU instruction
+//
+//	15 14 13 12 11 10  9  8  7  6  5  4  3  2  1  0
+//	 │  │  │  │  │  │  │  │  │  │  │  │  │  │  └──┴── dstack ±
+//	 │  │  │  │  │  │  │  │  │  │  │  │  └──┴──────── rstack ±
+//	 │  │  │  │  │  │  │  │  │  └──┴──┴────────────── func
+//	 │  │  │  │  │  │  │  │  └─────────────────────── R → PC
+//	 │  │  │  └──┴──┴──┴──────────────────────────── Tʹ
+//	 └──┴──┴───────────────────────────────────────── 0 1 1
+//
+// func is one of: 1 T → N, 2 T → R, 3 N → [T], 4 N → io[T]
+
+// j1bOps maps Tʹ field to opcode, J1b has no T-1
+var j1bOps = [16]Op{
+	OpT, OpN, OpTplusN, OpTandN, OpTorN, OpTxorN, OpNotT, OpNeqT,
+	OpNleT, OpNrshiftT, OpNlshiftT, OpR, OpAtT, OpIoAtT, OpDepth, OpNuleT,
+}
+
+const (
+	funcTtoN = 1 + iota
+	funcTtoR
+	funcNtoAtT
+	funcNtoIoAtT
+)
+
+func newALUj1b(v uint16) ALU {
+	fn := (v >> 4) & 7
+	return ALU{
+		Opcode:   j1bOps[(v>>8)&15],
+		RtoPC:    v&(1<<7) != 0,
+		TtoN:     fn == funcTtoN,
+		TtoR:     fn == funcTtoR,
+		NtoAtT:   fn == funcNtoAtT,
+		NtoIoAtT: fn == funcNtoIoAtT,
+		Rdir:     expand[(v>>2)&3],
+		Ddir:     expand[(v>>0)&3],
+	}
+}
+
+// compileJ1b encodes ALU instruction, opcodes and combinations J1b does
+// not have are lost
+func (v ALU) compileJ1b() uint16 {
+	var ret uint16 = 3 << 13
+	for i, op := range j1bOps {
+		if op == v.Opcode {
+			ret |= uint16(i) << 8
+		}
+	}
+	if v.RtoPC {
+		ret |= 1 << 7
+	}
+	switch {
+	case v.TtoN:
+		ret |= funcTtoN << 4
+	case v.TtoR:
+		ret |= funcTtoR << 4
+	case v.NtoAtT:
+		ret |= funcNtoAtT << 4
+	case v.NtoIoAtT:
+		ret |= funcNtoIoAtT << 4
+	}
+	ret |= uint16(v.Rdir&3) << 2
+	ret |= uint16(v.Ddir&3) << 0
+	return ret
+}
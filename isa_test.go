@@ -0,0 +1,98 @@
+package j1
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestJ1b(t *testing.T) {
+	// encodings of docs/j1/toolchain/basewords.fs
+	testCases := []struct {
+		bin uint16
+		ins Instruction
+	}{
+		{0x0123, Jump(0x0123)},
+		{0x8005, Literal(0x0005)},
+		{0x608c, ALU{Opcode: OpT, RtoPC: true, Rdir: -1}},             // exit
+		{0x6d00, ALU{Opcode: OpIoAtT}},                                // io@
+		{0x6043, ALU{Opcode: OpT, NtoIoAtT: true, Ddir: -1}},          // io!
+		{0x6033, ALU{Opcode: OpT, NtoAtT: true, Ddir: -1}},            // !
+		{0x6011, ALU{Opcode: OpT, TtoN: true, Ddir: 1}},               // dup
+		{0x6127, ALU{Opcode: OpN, TtoR: true, Rdir: 1, Ddir: -1}},     // >r
+		{0x6b1d, ALU{Opcode: OpR, TtoN: true, Rdir: -1, Ddir: 1}},     // r>
+		{0x6a03, ALU{Opcode: OpNlshiftT, Ddir: -1}},                   // lshift
+		{0x6e11, ALU{Opcode: OpDepth, TtoN: true, Ddir: 1}},           // depths
+		{0x6f03, ALU{Opcode: OpNuleT, Ddir: -1}},                      // u<
+		{0x6c11, ALU{Opcode: OpAtT, TtoN: true, Ddir: 1}},             // dup@
+		{0x60ac, ALU{Opcode: OpT, RtoPC: true, TtoR: true, Rdir: -1}}, // odd but valid
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.ins), func(t *testing.T) {
+			ins := J1b.Decode(tc.bin)
+			if ins != tc.ins {
+				t.Errorf("got %v, want %v", ins, tc.ins)
+			}
+			if v := J1b.Encode(ins); v != tc.bin {
+				t.Errorf("got %0.4X, want %0.4X", v, tc.bin)
+			}
+		})
+	}
+}
+
+func TestJ1bIO(t *testing.T) {
+	dev := mocDevice{}
+	j1 := New(&mocConsole{})
+	j1.SetISA(J1b)
+	j1.Attach(0, 2, dev)
+	prog := []uint16{
+		0x802a, // 42
+		0x8000, // 0
+		0x6043, // io!
+		0x6103, // drop
+		0x8000, // 0
+		0x6d00, // io@
+	}
+	for i, v := range prog {
+		j1.memory[i] = v
+	}
+	for range prog {
+		j1.Execute(j1.Fetch())
+	}
+	if dev[0] != 42 {
+		t.Errorf("io!: got %v, want 42", dev[0])
+	}
+	if j1.st0 != 42 {
+		t.Errorf("io@: got %v, want 42", j1.st0)
+	}
+	if j1.memory[0] != 0x802a {
+		t.Errorf("io! hit memory")
+	}
+}
+
+func TestParseISA(t *testing.T) {
+	for _, isa := range []ISA{J1, J1b} {
+		v, err := ParseISA(isa.String())
+		if err != nil || v != isa {
+			t.Errorf("got %v %v, want %v", v, err, isa)
+		}
+	}
+	if _, err := ParseISA("j2"); err == nil {
+		t.Error("want error")
+	}
+}
+
+func TestJ1Lost(t *testing.T) {
+	// J1b only opcode and field do not leak into other bits
+	testCases := []struct {
+		ins ALU
+		bin uint16
+	}{
+		{ALU{Opcode: OpIoAtT}, 0x6000},
+		{ALU{Opcode: OpT, NtoIoAtT: true, Ddir: -1}, 0x6003},
+	}
+	for _, tc := range testCases {
+		if v := J1.Encode(tc.ins); v != tc.bin {
+			t.Errorf("%v: got %0.4X, want %0.4X", tc.ins, v, tc.bin)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package loader reads J1 memory images
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Format of memory image
+type Format int
+
+// Known formats
+const (
+	Auto Format = iota // guess by file name and content
+	Bin                // raw little-endian cells
+	Mem                // one hex cell per line, as read by $readmemh
+	IHex               // Intel HEX
+)
+
+var formatNames = map[string]Format{
+	"auto": Auto,
+	"bin":  Bin,
+	"mem":  Mem,
+	"ihex": IHex,
+}
+
+func (f Format) String() string {
+	for k, v := range formatNames {
+		if v == f {
+			return k
+		}
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat by name: auto, bin, mem or ihex
+func ParseFormat(s string) (Format, error) {
+	if f, ok := formatNames[s]; ok {
+		return f, nil
+	}
+	return Auto, fmt.Errorf("unknown format %q", s)
+}
+
+// memSize in bytes, 13 bit cell address
+const memSize = 2 << 13
+
+// Load image from file, returns bytes for Core.Write. Raw images are passed
+// as is, Core.Write rejects them if of odd length.
+func Load(name string, f Format) ([]byte, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if f == Auto {
+		f = guess(name, data)
+	}
+	switch f {
+	case Bin:
+		return data, nil
+	case Mem:
+		return ReadMem(bytes.NewReader(data))
+	case IHex:
+		return ReadIHex(bytes.NewReader(data))
+	}
+	return nil, fmt.Errorf("unknown format %v", f)
+}
+
+// guess format, .hex is used for both $readmemh and Intel HEX files
+func guess(name string, data []byte) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".mem":
+		return Mem
+	case ".hex", ".ihx", ".ihex":
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte(":")) {
+			return IHex
+		}
+		return Mem
+	}
+	return Bin
+}
+
+// image collects cells at arbitrary addresses
+type image []byte
+
+func (m *image) put(addr int, v byte) error {
+	if addr >= memSize {
+		return fmt.Errorf("address %#x out of memory", addr)
+	}
+	if addr >= len(*m) {
+		*m = append(*m, make([]byte, addr+1-len(*m))...)
+	}
+	(*m)[addr] = v
+	return nil
+}
+
+// cells of image, padded to whole last cell as Core.Write expects
+func (m image) cells() []byte {
+	if len(m)%2 != 0 {
+		return append(m, 0)
+	}
+	return m
+}
+
+// ReadMem reads $readmemh style image: hex cells separated by white space,
+// @ sets cell address. As on 13 bit address bus, high address bits are
+// ignored.
+func ReadMem(r io.Reader) ([]byte, error) {
+	var m image
+	addr := 0
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		t, _, _ := strings.Cut(s.Text(), "//")
+		words := strings.Fields(t)
+		for i := 0; i < len(words); i++ {
+			w := words[i]
+			if w == "@" && i+1 < len(words) {
+				i++
+				w += words[i]
+			}
+			if strings.HasPrefix(w, "@") {
+				v, err := strconv.ParseUint(w[1:], 16, 32)
+				if err != nil {
+					return nil, fmt.Errorf("mem: line %d: %w", line, err)
+				}
+				addr = int(v) & (memSize/2 - 1)
+				continue
+			}
+			v, err := strconv.ParseUint(w, 16, 16)
+			if err != nil {
+				return nil, fmt.Errorf("mem: line %d: %w", line, err)
+			}
+			if err := m.put(2*addr, byte(v)); err != nil {
+				return nil, fmt.Errorf("mem: line %d: %w", line, err)
+			}
+			if err := m.put(2*addr+1, byte(v>>8)); err != nil {
+				return nil, fmt.Errorf("mem: line %d: %w", line, err)
+			}
+			addr++
+		}
+	}
+	return m, s.Err()
+}
+
+// Intel HEX record types
+const (
+	recData        = 0x00
+	recEOF         = 0x01
+	recSegment     = 0x02
+	recStartSeg    = 0x03
+	recLinear      = 0x04
+	recStartLinear = 0x05
+)
+
+// ReadIHex reads Intel HEX image, data bytes are taken as byte addressed
+// memory. Image is padded to a whole number of cells.
+func ReadIHex(r io.Reader) ([]byte, error) {
+	var m image
+	base := 0
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		t := strings.TrimSpace(s.Text())
+		if t == "" {
+			continue
+		}
+		if !strings.HasPrefix(t, ":") {
+			return nil, fmt.Errorf("ihex: line %d: missing start code", line)
+		}
+		rec, err := hex.DecodeString(t[1:])
+		if err != nil {
+			return nil, fmt.Errorf("ihex: line %d: %w", line, err)
+		}
+		if len(rec) < 5 || len(rec) != 5+int(rec[0]) {
+			return nil, fmt.Errorf("ihex: line %d: bad record length", line)
+		}
+		var sum byte
+		for _, v := range rec {
+			sum += v
+		}
+		if sum != 0 {
+			return nil, fmt.Errorf("ihex: line %d: bad checksum", line)
+		}
+		addr := int(rec[1])<<8 | int(rec[2])
+		data := rec[4 : len(rec)-1]
+		switch rec[3] {
+		case recData:
+			for i, v := range data {
+				if err := m.put(base+addr+i, v); err != nil {
+					return nil, fmt.Errorf("ihex: line %d: %w", line, err)
+				}
+			}
+		case recEOF:
+			return m.cells(), nil
+		case recSegment:
+			if len(data) != 2 {
+				return nil, fmt.Errorf("ihex: line %d: bad segment record", line)
+			}
+			base = (int(data[0])<<8 | int(data[1])) << 4
+		case recLinear:
+			if len(data) != 2 {
+				return nil, fmt.Errorf("ihex: line %d: bad linear address record", line)
+			}
+			base = (int(data[0])<<8 | int(data[1])) << 16
+		case recStartSeg, recStartLinear:
+		default:
+			return nil, fmt.Errorf("ihex: line %d: unknown record type %#x", line, rec[3])
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("ihex: missing end of file record")
+}
@@ -0,0 +1,103 @@
+package loader
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadMem(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want []byte
+		err  bool
+	}{
+		{in: "1C5D\n6E81\n", want: []byte{0x5d, 0x1c, 0x81, 0x6e}},
+		{in: "@ 20000\n1C5D", want: []byte{0x5d, 0x1c}},
+		{in: "@2 1 // comment\n2", want: []byte{0, 0, 0, 0, 1, 0, 2, 0}},
+		{in: "12345", err: true},
+		{in: "@", err: true},
+		{in: "@zz", err: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ReadMem(strings.NewReader(tc.in))
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v", err)
+			}
+			if !bytes.Equal(got, tc.want) {
+				t.Errorf("got %x, want %x", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadIHex(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want []byte
+		err  bool
+	}{
+		{in: ":0400000001020304F2\n:00000001FF\n", want: []byte{1, 2, 3, 4}},
+		{in: ":020000021000EC\n:01000000AA55\n:00000001FF", err: true}, // 0x10000 out of memory
+		{in: ":020000020001FB\n:01000000AA55\n:00000001FF", want: append(make([]byte, 16), 0xaa, 0)},
+		{in: ":0100000001FF\n:00000001FF", err: true}, // bad checksum
+		{in: "0100000001FE", err: true},
+		{in: ":01000000", err: true},
+		{in: ":0100000001FE\n", err: true}, // no EOF
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ReadIHex(strings.NewReader(tc.in))
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v", err)
+			}
+			if !bytes.Equal(got, tc.want) {
+				t.Errorf("got %x, want %x", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.bin":  "\x01\x02",
+		"a.mem":  "0201",
+		"a.hex":  "0201",
+		"b.hex":  ":020000000102FB\n:00000001FF",
+		"a.ihex": ":020000000102FB\n:00000001FF",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Load(filepath.Join(dir, name), Auto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []byte{1, 2}; !bytes.Equal(got, want) {
+			t.Errorf("%v: got %x, want %x", name, got, want)
+		}
+	}
+	odd := filepath.Join(dir, "odd.bin")
+	if err := os.WriteFile(odd, []byte{1}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// raw images are passed as is, Core.Write rejects odd length
+	if got, err := Load(odd, Auto); err != nil || !bytes.Equal(got, []byte{1}) {
+		t.Errorf("odd.bin: got %x, %v, want 01", got, err)
+	}
+	got, err := Load("../testdata/j1.mem", Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != memSize {
+		t.Errorf("got %v bytes, want %v", len(got), memSize)
+	}
+	if _, err := ParseFormat("elf"); err == nil {
+		t.Error("want error")
+	}
+}
@@ -1,44 +1,48 @@
 package j1
 
+// Op is ALU operation, Tʹ field of instruction
 type Op uint8
 
+// Operations, by classic J1 encoding
 const (
-	opT Op = iota
-	opN
-	opTplusN
-	opTandN
-	opTorN
-	opTxorN
-	opNotT
-	opNeqT
-	opNleT
-	opNrshiftT
-	opTminus1
-	opR
-	opAtT
-	opNlshiftT
-	opDepth
-	opNuleT
+	OpT Op = iota
+	OpN
+	OpTplusN
+	OpTandN
+	OpTorN
+	OpTxorN
+	OpNotT
+	OpNeqT
+	OpNleT
+	OpNrshiftT
+	OpTminus1
+	OpR
+	OpAtT
+	OpNlshiftT
+	OpDepth
+	OpNuleT
+	OpIoAtT // J1b only
 	nOps
 )
 
 var opcodeNames = [nOps]string{
-	opT:        "T",
-	opN:        "N",
-	opTplusN:   "T+N",
-	opTandN:    "T∧N",
-	opTorN:     "T∨N",
-	opTxorN:    "T⊻N",
-	opNotT:     "¬T",
-	opNeqT:     "N=T",
-	opNleT:     "N<T",
-	opNrshiftT: "N≫T",
-	opTminus1:  "T-1",
-	opR:        "R",
-	opAtT:      "[T]",
-	opNlshiftT: "N≪T",
-	opDepth:    "D",
-	opNuleT:    "Nu<T",
+	OpT:        "T",
+	OpN:        "N",
+	OpTplusN:   "T+N",
+	OpTandN:    "T∧N",
+	OpTorN:     "T∨N",
+	OpTxorN:    "T⊻N",
+	OpNotT:     "¬T",
+	OpNeqT:     "N=T",
+	OpNleT:     "N<T",
+	OpNrshiftT: "N≫T",
+	OpTminus1:  "T-1",
+	OpR:        "R",
+	OpAtT:      "[T]",
+	OpNlshiftT: "N≪T",
+	OpDepth:    "D",
+	OpNuleT:    "Nu<T",
+	OpIoAtT:    "io[T]",
 }
 
 func (op Op) String() string {
@@ -0,0 +1,146 @@
+package j1
+
+// peephole patterns
+var (
+	retALU  = ALU{Opcode: OpT, RtoPC: true, Rdir: -1}
+	plusALU = ALU{Opcode: OpTplusN, Ddir: -1}
+)
+
+// Optimize instruction stream code, which starts at cell address org.
+// A return fuses into preceding ALU instruction which leaves return stack
+// alone, a call followed by return becomes a jump, literals followed by
+// addition are folded and unreachable instructions after unconditional
+// jumps and returns are dropped. Nothing is done across branch targets.
+//
+// Targets of branches and calls within code are found and relocated, other
+// addresses jumped into must be given as cell addresses in entries. They
+// are returned relocated, in same order. Code must not contain inline data.
+func Optimize(code []Instruction, org uint16, entries ...uint16) ([]Instruction, []uint16) {
+	n := len(code)
+	inside := func(a uint16) bool { return a >= org && int(a-org) <= n }
+	// offsets are kept relative to original code throughout
+	labels := map[int]bool{0: true, n: true}
+	for _, a := range entries {
+		if inside(a) {
+			labels[int(a-org)] = true
+		}
+	}
+	for _, ins := range code {
+		if a, ok := target(ins); ok && inside(a) {
+			labels[int(a-org)] = true
+		}
+	}
+	out := append([]Instruction(nil), code...)
+	orig := make([]int, n)
+	for i := range orig {
+		orig[i] = i
+	}
+	// at is true when instruction i may be jumped to
+	at := func(i int) bool { return labels[orig[i]] }
+	remove := func(i, k int) {
+		out = append(out[:i], out[i+k:]...)
+		orig = append(orig[:i], orig[i+k:]...)
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(out); i++ {
+			rest := out[i:]
+			switch {
+			case len(rest) > 1 && !at(i+1) && rest[1] == retALU && fusable(rest[0]):
+				alu := rest[0].(ALU)
+				alu.RtoPC, alu.Rdir = true, -1
+				out[i] = alu
+				remove(i+1, 1)
+			case len(rest) > 1 && !at(i+1) && rest[1] == retALU && calls(rest[0]):
+				out[i] = Jump(rest[0].(Call))
+				remove(i+1, 1)
+			case len(rest) > 2 && !at(i+1) && !at(i+2) && rest[2] == plusALU &&
+				sumOf(rest[0], rest[1]) < 0x8000:
+				out[i] = Literal(sumOf(rest[0], rest[1]))
+				remove(i+1, 2)
+			case len(rest) > 3 && !at(i+1) && !at(i+2) && !at(i+3) &&
+				rest[1] == plusALU && rest[3] == plusALU && sumOf(rest[0], rest[2]) < 0x8000:
+				out[i] = Literal(sumOf(rest[0], rest[2]))
+				remove(i+2, 2)
+			case len(rest) > 1 && !at(i+1) && rest[0] == Literal(0) && rest[1] == plusALU:
+				remove(i, 2)
+			case len(rest) > 1 && !at(i+1) && ends(rest[0]):
+				remove(i+1, 1)
+			default:
+				continue
+			}
+			changed = true
+		}
+	}
+	// relocate maps original offset to new address
+	relocate := func(a uint16) uint16 {
+		if !inside(a) {
+			return a
+		}
+		i := 0
+		for i < len(orig) && orig[i] < int(a-org) {
+			i++
+		}
+		return org + uint16(i)
+	}
+	for i, ins := range out {
+		switch v := ins.(type) {
+		case Jump:
+			out[i] = Jump(relocate(uint16(v)))
+		case Conditional:
+			out[i] = Conditional(relocate(uint16(v)))
+		case Call:
+			out[i] = Call(relocate(uint16(v)))
+		}
+	}
+	moved := make([]uint16, len(entries))
+	for i, a := range entries {
+		moved[i] = relocate(a)
+	}
+	return out, moved
+}
+
+// target of branch or call
+func target(ins Instruction) (uint16, bool) {
+	switch v := ins.(type) {
+	case Jump:
+		return uint16(v), true
+	case Conditional:
+		return uint16(v), true
+	case Call:
+		return uint16(v), true
+	}
+	return 0, false
+}
+
+// fusable ALU instruction leaves return stack and PC alone
+func fusable(ins Instruction) bool {
+	v, ok := ins.(ALU)
+	return ok && !v.RtoPC && !v.TtoR && v.Rdir == 0
+}
+
+func calls(ins Instruction) bool {
+	_, ok := ins.(Call)
+	return ok
+}
+
+// ends is true if no instruction after ins is reached by falling through
+func ends(ins Instruction) bool {
+	switch v := ins.(type) {
+	case Jump:
+		return true
+	case ALU:
+		return v.RtoPC
+	}
+	return false
+}
+
+// sumOf two literals, or 0x8000 if not both are literals
+func sumOf(a, b Instruction) uint16 {
+	x, ok := a.(Literal)
+	y, ok2 := b.(Literal)
+	if !ok || !ok2 {
+		return 0x8000
+	}
+	return uint16(x) + uint16(y)
+}
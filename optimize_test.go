@@ -0,0 +1,116 @@
+package j1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	var (
+		dup  = ALU{Opcode: OpT, TtoN: true, Ddir: 1}
+		toR  = ALU{Opcode: OpN, TtoR: true, Ddir: -1, Rdir: 1}
+		exit = retALU
+		// dup ; fused
+		dupExit = ALU{Opcode: OpT, TtoN: true, RtoPC: true, Rdir: -1, Ddir: 1}
+		plus    = plusALU
+	)
+	testCases := []struct {
+		org     uint16
+		entries []uint16
+		ins     []Instruction
+		want    []Instruction
+		moved   []uint16
+	}{
+		{ // dup ;
+			ins:  []Instruction{dup, exit},
+			want: []Instruction{dupExit},
+		},
+		{ // >r ; touches R
+			ins:  []Instruction{toR, exit},
+			want: []Instruction{toR, exit},
+		},
+		{ // tail call
+			ins:  []Instruction{Call(0x100), exit},
+			want: []Instruction{Jump(0x100)},
+		},
+		{ // literals folded
+			ins:  []Instruction{Literal(1), Literal(2), plus, exit},
+			want: []Instruction{Literal(3), exit},
+		},
+		{ // additions merged
+			ins:  []Instruction{Literal(1), plus, Literal(2), plus, exit},
+			want: []Instruction{Literal(3), ALU{Opcode: OpTplusN, RtoPC: true, Rdir: -1, Ddir: -1}},
+		},
+		{ // no zero added
+			ins:  []Instruction{dup, Literal(0), plus, exit},
+			want: []Instruction{dupExit},
+		},
+		{ // would overflow into instruction bit
+			ins:  []Instruction{Literal(0x7fff), Literal(1), plus},
+			want: []Instruction{Literal(0x7fff), Literal(1), plus},
+		},
+		{ // dead code dropped, branch relocated
+			org:  0x10,
+			ins:  []Instruction{Conditional(0x14), Jump(0x200), exit, exit, dup, exit},
+			want: []Instruction{Conditional(0x12), Jump(0x200), dupExit},
+		},
+		{ // return is a branch target
+			org:  0x10,
+			ins:  []Instruction{Conditional(0x12), dup, exit},
+			want: []Instruction{Conditional(0x12), dup, exit},
+		},
+		{ // entry kept and moved
+			org:     0x10,
+			entries: []uint16{0x14},
+			ins:     []Instruction{Literal(0), plus, Jump(0x20), exit, dup, exit},
+			want:    []Instruction{Jump(0x20), dupExit},
+			moved:   []uint16{0x11},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.ins), func(t *testing.T) {
+			got, moved := Optimize(tc.ins, tc.org, tc.entries...)
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+			if fmt.Sprint(moved) != fmt.Sprint(tc.moved) {
+				t.Errorf("entries: got %v, want %v", moved, tc.moved)
+			}
+		})
+	}
+}
+
+// TestOptimizeRun compares results of plain and optimized code
+func TestOptimizeRun(t *testing.T) {
+	plus := plusALU
+	// f: 3 + 4 + 0 + if 1 else 2 then g ; g: dup + ;
+	code := []Instruction{
+		Literal(3), Literal(4), plus, Literal(0), plus,
+		Conditional(0x08), Literal(1), Jump(0x09),
+		Literal(2), Call(0x0b), retALU,
+		ALU{Opcode: OpT, TtoN: true, Ddir: 1}, plus, retALU,
+	}
+	run := func(code []Instruction, entry uint16) []uint16 {
+		t.Helper()
+		c := New(&mocConsole{})
+		for i, ins := range code {
+			c.memory[i] = Encode(ins)
+		}
+		res, err := c.CallAt(context.Background(), entry<<1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	opt, moved := Optimize(code, 0, 0x0b)
+	if len(opt) >= len(code) {
+		t.Errorf("not smaller: %v", opt)
+	}
+	for _, entry := range [][2]uint16{{0, 0}, {0x0b, moved[0]}} {
+		want := run(code, entry[0])
+		if got := run(opt, entry[1]); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%0.4X: got %v, want %v", entry[0], got, want)
+		}
+	}
+}
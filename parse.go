@@ -13,10 +13,8 @@ func Decode(v uint16) Instruction {
 		return newConditional(v)
 	case isCall(v):
 		return newCall(v)
-	case isALU(v):
-		return newALU(v)
 	default:
-		panic("invalid instruction")
+		return newALU(v)
 	}
 }
 
@@ -96,13 +94,14 @@ func (v Call) compile() uint16 { return v.value() | (2 << 13) }
 //	 │  │  │  └────────────────────────────────────── R → PC
 //	 └──┴──┴───────────────────────────────────────── 0 1 1
 type ALU struct {
-	Opcode Op
-	RtoPC  bool
-	TtoN   bool
-	TtoR   bool
-	NtoAtT bool
-	Rdir   int8
-	Ddir   int8
+	Opcode   Op
+	RtoPC    bool
+	TtoN     bool
+	TtoR     bool
+	NtoAtT   bool
+	NtoIoAtT bool // J1b only
+	Rdir     int8
+	Ddir     int8
 }
 
 // expand 2 bit unsigned to 8 bit signed
@@ -122,8 +121,13 @@ func newALU(v uint16) ALU {
 
 func isALU(v uint16) bool { return v&(7<<13) == 3<<13 }
 
+// value encodes classic J1 ALU instruction, opcodes and fields J1 does
+// not have are lost
 func (v ALU) value() uint16 {
-	ret := uint16(v.Opcode) << 8
+	var ret uint16
+	if v.Opcode < 16 {
+		ret |= uint16(v.Opcode) << 8
+	}
 	if v.RtoPC {
 		ret |= 1 << 12
 	}
@@ -157,6 +161,9 @@ func (v ALU) String() string {
 	if v.NtoAtT {
 		s += " N→[T]"
 	}
+	if v.NtoIoAtT {
+		s += " N→io[T]"
+	}
 	if v.Rdir != 0 {
 		s += fmt.Sprintf(" r%+d", v.Rdir)
 	}
@@ -0,0 +1,77 @@
+package j1
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Perm is access permitted to memory cells
+type Perm uint8
+
+// Permissions, combined
+const (
+	PermRead Perm = 1 << iota
+	PermWrite
+	PermExec
+
+	NoAccess  Perm = 0
+	ReadOnly       = PermRead | PermExec // code
+	Data           = PermRead | PermWrite
+	ReadWrite      = PermRead | PermWrite | PermExec
+)
+
+// Errors of protected memory access, wrapped in Fault
+var (
+	ErrWriteProtected = errors.New("write to protected memory")
+	ErrReadProtected  = errors.New("read of protected memory")
+	ErrNotExecutable  = errors.New("fetch from data memory")
+)
+
+// Protect RAM at byte addresses base..base+size-1 with perm. Protection is
+// off until first call, then cells not given otherwise are ReadWrite.
+// Stores and reads through T, and fetches, fault where perm does not allow
+// them. Loading an image with Write ignores protection. In a System, all
+// cores share protection.
+func (c *Core) Protect(base, size uint16, perm Perm) error {
+	end := int(base) + int(size)
+	if base&ioMask != 0 || end > 2*len(c.memory) {
+		return fmt.Errorf("%0.4X..%0.4X: not in RAM", base, end-1)
+	}
+	if c.prot == nil {
+		prot := new([8192]Perm)
+		for i := range prot {
+			prot[i] = ReadWrite
+		}
+		for _, p := range c.group() {
+			p.prot = prot
+		}
+	}
+	for i := int(base) >> 1; i < (end+1)>>1; i++ {
+		c.prot[i] = perm
+	}
+	for _, p := range c.group() {
+		p.flush()
+	}
+	return nil
+}
+
+// executable tells if cell at pc may be fetched
+func (c *Core) executable(pc uint16) bool {
+	return c.prot == nil || c.prot[pc]&PermExec != 0
+}
+
+// access checks memory at T instruction v reads or writes
+func (c *Core) access(v *ALU) error {
+	T := c.st0
+	if c.prot == nil || T&ioMask != 0 {
+		return nil
+	}
+	p := c.prot[T>>1]
+	switch {
+	case v.NtoAtT && p&PermWrite == 0:
+		return fmt.Errorf("%w at %0.4X", ErrWriteProtected, T)
+	case v.Opcode == OpAtT && p&PermRead == 0:
+		return fmt.Errorf("%w at %0.4X", ErrReadProtected, T)
+	}
+	return nil
+}
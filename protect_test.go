@@ -0,0 +1,76 @@
+package j1
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProtect(t *testing.T) {
+	store := ALU{Opcode: OpN, NtoAtT: true, Ddir: -1}
+	fetch := ALU{Opcode: OpAtT}
+	testCases := []struct {
+		name       string
+		base, size uint16
+		perm       Perm
+		prog       []Instruction
+		addr       uint16
+		err        error
+	}{
+		{name: "write", base: 0, size: 0x20, perm: ReadOnly,
+			prog: []Instruction{Literal(1), Literal(0x10), store}, addr: 4, err: ErrWriteProtected},
+		{name: "read", base: 0x100, size: 2, perm: NoAccess,
+			prog: []Instruction{Literal(0x100), fetch}, addr: 2, err: ErrReadProtected},
+		{name: "fetch", base: 0x40, size: 0x40, perm: Data,
+			prog: []Instruction{Literal(1), Jump(0x20)}, addr: 0x40, err: ErrNotExecutable},
+		{name: "data", base: 0x100, size: 0x100, perm: Data,
+			prog: []Instruction{Literal(5), Literal(0x100), store, fetch, Jump(0)}},
+	}
+	for _, tc := range testCases {
+		for _, e := range []string{"execute", "interp"} {
+			t.Run(tc.name+"/"+e, func(t *testing.T) {
+				c := New(&mocConsole{})
+				for i, ins := range tc.prog {
+					c.memory[i] = Encode(ins)
+				}
+				if err := c.Protect(tc.base, tc.size, tc.perm); err != nil {
+					t.Fatal(err)
+				}
+				for c.cycles < 100 && c.Err() == nil {
+					switch e {
+					case "execute":
+						c.Execute(c.Fetch())
+					case "interp":
+						c.run(100 - int(c.cycles))
+					}
+				}
+				err := c.Err()
+				if tc.err == nil {
+					if err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				var f *Fault
+				if !errors.Is(err, tc.err) || !errors.As(err, &f) || f.Addr != tc.addr {
+					t.Fatalf("got %v, want %0.4X: %v", err, tc.addr, tc.err)
+				}
+				if c.pc != tc.addr>>1 || c.memory[8] != 0 {
+					t.Errorf("pc %0.4X, memory %v", c.pc<<1, c.memory[8])
+				}
+			})
+		}
+	}
+}
+
+func TestProtectRange(t *testing.T) {
+	c := New(&mocConsole{})
+	if err := c.Protect(0x3f00, 0x200, ReadOnly); err == nil {
+		t.Error("want error")
+	}
+	if err := c.Protect(0x3f00, 0x100, ReadOnly); err != nil {
+		t.Error(err)
+	}
+	if c.prot[0x1f80] != ReadOnly || c.prot[0x1f7f] != ReadWrite {
+		t.Error("wrong range")
+	}
+}
@@ -0,0 +1,233 @@
+package j1
+
+import "fmt"
+
+// Reference model of the CPU in docs/j1eforth/j1.c, ported statement by
+// statement. It differs from Core where j1.c does:
+//
+//	whole 0..0x7fff is memory, only 0x7000..0x7002 are i/o
+//	0x7001 (?rx) always reads 1
+//	write to 0x7002 (bye) clears return stack pointer
+//	shifts take T as is, not T mod 16, as on x86
+//	[T] reads memory before N→[T] of same instruction writes it
+//	program counter has 16 bits, not 13
+//
+// Addresses past memory wrap around, where j1.c reads or writes out of
+// bounds.
+type Reference struct {
+	T        uint16
+	D, R     [32]uint16 // data and return stacks
+	DSP, RSP uint8      // point to top entry
+	PC       uint16     // counts cells
+	Memory   [0x4000]uint16
+	Key      func() uint16 // getch, on read of 0x7000
+	Emit     func(uint16)  // putch, on write to 0x7000
+}
+
+// Reference model with state of core. Memory mapped i/o is left empty.
+func (c *Core) Reference() *Reference {
+	m := &Reference{
+		T:   c.st0,
+		D:   c.d.data,
+		R:   c.r.data,
+		DSP: c.d.sp,
+		RSP: c.r.sp,
+		PC:  c.pc,
+	}
+	copy(m.Memory[:], c.memory[:])
+	return m
+}
+
+// Boot maps script to 0x4000 as main of j1.c does: size in the first cell,
+// content in the following ones.
+func (m *Reference) Boot(script []byte) {
+	io := m.Memory[0x2000:]
+	io[0] = uint16(len(script))
+	for i, v := range script {
+		if 1+i/2 < len(io) {
+			io[1+i/2] |= uint16(v) << (8 * (i % 2))
+		}
+	}
+}
+
+var sx = [4]uint8{0, 1, 0x1e, 0x1f} // 2-bit sign extension, mod 32
+
+func (m *Reference) push(v uint16) {
+	m.DSP = 0x1f & (m.DSP + 1)
+	m.D[m.DSP] = m.T
+	m.T = v
+}
+
+func (m *Reference) pop() uint16 {
+	v := m.T
+	m.T = m.D[m.DSP]
+	m.DSP = 0x1f & (m.DSP - 1)
+	return v
+}
+
+func (m *Reference) cell(addr uint16) *uint16 {
+	return &m.Memory[(addr>>1)&0x3fff]
+}
+
+// Step executes instruction at PC, as loop of execute in j1.c does
+func (m *Reference) Step() {
+	insn := *m.cell(m.PC << 1)
+	pc := m.PC + 1
+	if insn&0x8000 != 0 { // literal
+		m.push(insn & 0x7fff)
+		m.PC = pc
+		return
+	}
+	target := insn & 0x1fff
+	switch insn >> 13 {
+	case 0: // jump
+		pc = target
+	case 1: // conditional jump
+		if m.pop() == 0 {
+			pc = target
+		}
+	case 2: // call
+		m.RSP = 0x1f & (m.RSP + 1)
+		m.R[m.RSP] = pc << 1
+		pc = target
+	case 3: // alu
+		if insn&0x1000 != 0 { // r->pc
+			pc = m.R[m.RSP] >> 1
+		}
+		t, s := m.T, m.D[m.DSP]
+		var nt uint16
+		switch (insn >> 8) & 0xf {
+		case 0:
+			nt = t
+		case 1:
+			nt = s
+		case 2:
+			nt = t + s
+		case 3:
+			nt = t & s
+		case 4:
+			nt = t | s
+		case 5:
+			nt = t ^ s
+		case 6:
+			nt = ^t
+		case 7:
+			nt = boolValue(t == s)
+		case 8:
+			nt = boolValue(int16(s) < int16(t))
+		case 9:
+			nt = uint16(uint32(s) >> (t & 0x1f))
+		case 0xa:
+			nt = t - 1
+		case 0xb:
+			nt = m.R[m.RSP]
+		case 0xc:
+			switch t {
+			case 0x7001:
+				nt = 1
+			case 0x7000:
+				if m.Key != nil {
+					nt = m.Key()
+				}
+			default:
+				nt = *m.cell(t)
+			}
+		case 0xd:
+			nt = uint16(uint32(s) << (t & 0x1f))
+		case 0xe:
+			nt = uint16(m.RSP)<<8 + uint16(m.DSP)
+		case 0xf:
+			nt = boolValue(s < t)
+		}
+		m.DSP = 0x1f & (m.DSP + sx[insn&3])
+		m.RSP = 0x1f & (m.RSP + sx[(insn>>2)&3])
+		if insn&0x80 != 0 { // t->s
+			m.D[m.DSP] = t
+		}
+		if insn&0x40 != 0 { // t->r
+			m.R[m.RSP] = t
+		}
+		if insn&0x20 != 0 { // s->[t]
+			switch t {
+			case 0x7002:
+				m.RSP = 0
+			case 0x7000:
+				if m.Emit != nil {
+					m.Emit(s)
+				}
+			default:
+				*m.cell(t) = s
+			}
+		}
+		m.T = nt
+	}
+	m.PC = pc
+}
+
+// Divergence of Core from Reference
+type Divergence struct {
+	Cycle     uint64 // instructions executed before
+	PC        uint16 // cell of instruction
+	Ins       uint16
+	T         uint16 // top of data stack before instruction
+	What      string // register, stack entry or memory cell
+	Core, Ref uint16
+}
+
+func (d *Divergence) String() string {
+	return fmt.Sprintf("cycle %v: %0.4X %0.4X %v with T=%0.4X: %v is %0.4X, j1.c has %0.4X",
+		d.Cycle, d.PC<<1, d.Ins, Decode(d.Ins), d.T, d.What, d.Core, d.Ref)
+}
+
+// Diff runs core and reference model side by side, one instruction at a
+// time, n instructions at most. It gives the first divergence in registers,
+// stacks or RAM written, nil if there is none.
+func Diff(c *Core, m *Reference, n uint64) *Divergence {
+	for k := uint64(0); k < n; k++ {
+		pc, ins := c.pc, *m.cell(m.PC << 1)
+		d := &Divergence{Cycle: k, PC: pc, Ins: ins, T: c.st0}
+		addr, store := c.st0, false
+		if v, ok := Decode(ins).(ALU); ok {
+			store = v.NtoAtT && addr&ioMask == 0
+		}
+		c.Execute(c.Fetch())
+		m.Step()
+		if v, ok := diffState(c, m); ok {
+			d.What, d.Core, d.Ref = v.what, v.core, v.ref
+			return d
+		}
+		if store && c.memory[addr>>1] != *m.cell(addr) {
+			d.What = fmt.Sprintf("[%0.4X]", addr&^1)
+			d.Core, d.Ref = c.memory[addr>>1], *m.cell(addr)
+			return d
+		}
+	}
+	return nil
+}
+
+type diff struct {
+	what      string
+	core, ref uint16
+}
+
+func diffState(c *Core, m *Reference) (diff, bool) {
+	switch {
+	case c.pc != m.PC:
+		return diff{"pc", c.pc, m.PC}, true
+	case c.st0 != m.T:
+		return diff{"T", c.st0, m.T}, true
+	case c.d.sp != m.DSP:
+		return diff{"dsp", uint16(c.d.sp), uint16(m.DSP)}, true
+	case c.r.sp != m.RSP:
+		return diff{"rsp", uint16(c.r.sp), uint16(m.RSP)}, true
+	}
+	for i := range c.d.data {
+		if c.d.data[i] != m.D[i] {
+			return diff{fmt.Sprintf("d[%d]", i), c.d.data[i], m.D[i]}, true
+		}
+		if c.r.data[i] != m.R[i] {
+			return diff{fmt.Sprintf("r[%d]", i), c.r.data[i], m.R[i]}, true
+		}
+	}
+	return diff{}, false
+}
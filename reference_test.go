@@ -0,0 +1,141 @@
+package j1
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestReference(t *testing.T) {
+	store := ALU{Opcode: OpN, NtoAtT: true, Ddir: -1}
+	testCases := []struct {
+		name      string
+		ins       []Instruction
+		boot      string
+		what      string
+		core, ref uint16
+	}{
+		{name: "1-", ins: []Instruction{Literal(5), ALU{Opcode: OpTminus1}}},
+		{name: "!", ins: []Instruction{Literal(7), Literal(0x100), store}},
+		{name: "call", ins: []Instruction{Call(1), Literal(1), ALU{Opcode: OpR, TtoN: true, Ddir: 1}}},
+		{name: "boot", ins: []Instruction{Literal(0x4002), ALU{Opcode: OpAtT}}, boot: "ok"},
+		{
+			name: "lshift",
+			ins:  []Instruction{Literal(1), Literal(16), ALU{Opcode: OpNlshiftT, Ddir: -1}},
+			what: "T", core: 1, ref: 0,
+		},
+		{
+			name: "rshift",
+			ins:  []Instruction{Literal(0x100), Literal(17), ALU{Opcode: OpNrshiftT, Ddir: -1}},
+			what: "T", core: 0x80, ref: 0,
+		},
+		{
+			name: "?rx",
+			ins:  []Instruction{Literal(0x7001), ALU{Opcode: OpAtT}},
+			what: "T", core: 0, ref: 1,
+		},
+		{
+			name: "bye",
+			ins:  []Instruction{Call(1), Literal(0), Literal(0x7002), store},
+			what: "rsp", core: 1, ref: 0,
+		},
+		{
+			name: "[T] and N→[T]",
+			ins:  []Instruction{Literal(7), Literal(0x100), ALU{Opcode: OpAtT, NtoAtT: true, TtoN: true}},
+			what: "T", core: 7, ref: 0,
+		},
+		{
+			name: "i/o",
+			ins:  []Instruction{Literal(0x100), Literal(0x5000), store, Literal(0x5000), ALU{Opcode: OpAtT}},
+			what: "T", core: 0, ref: 0x100,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New(&mocConsole{})
+			for i, ins := range tc.ins {
+				c.memory[i] = Encode(ins)
+			}
+			if tc.boot != "" {
+				if err := c.Boot([]byte(tc.boot)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			m := c.Reference()
+			m.Boot([]byte(tc.boot))
+			d := Diff(c, m, uint64(len(tc.ins)))
+			switch {
+			case d == nil && tc.what != "":
+				t.Errorf("no divergence, want %v", tc.what)
+			case d != nil && (d.What != tc.what || d.Core != tc.core || d.Ref != tc.ref):
+				t.Errorf("got %v", d)
+			}
+		})
+	}
+}
+
+func TestReferenceEForth(t *testing.T) {
+	script := []byte(": sq dup * ;\n7 sq 3000 ! bye")
+	c := New(&mocConsole{})
+	if _, err := c.Write(eforthImage(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Boot(script); err != nil {
+		t.Fatal(err)
+	}
+	m := c.Reference()
+	m.Boot(script)
+	// eForth relies on none of j1.c quirks up to bye
+	d := Diff(c, m, 1e6)
+	if d == nil || d.What != "rsp" || d.T != 0x7002 {
+		t.Fatalf("got %v, want bye", d)
+	}
+	if v := c.memory[0x3000>>1]; v != 49 {
+		t.Errorf("got %v, want 49", v)
+	}
+}
+
+// known tells if divergence is one listed at Reference
+func known(d *Divergence) bool {
+	if d.What == "pc" && d.Ref > pcMask {
+		return true
+	}
+	v, ok := Decode(d.Ins).(ALU)
+	if !ok {
+		return false
+	}
+	switch {
+	case (v.Opcode == OpNlshiftT || v.Opcode == OpNrshiftT) && d.T >= 16:
+		return true
+	case (v.Opcode == OpAtT || v.NtoAtT) && d.T&ioMask != 0:
+		return true
+	case v.Opcode == OpAtT && v.NtoAtT:
+		return true
+	}
+	return false
+}
+
+func TestReferenceRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	diverged := 0
+	for i := 0; i < 1000; i++ {
+		c := New(&mocConsole{})
+		for k := 0; k < 64; k++ {
+			v := uint16(rnd.Intn(1 << 16))
+			if v < 0x6000 {
+				v &^= 0x1fc0 // keep branches inside program
+			}
+			c.memory[k] = v
+		}
+		for k := range c.d.data {
+			c.d.data[k] = uint16(rnd.Intn(64))
+			c.r.data[k] = uint16(rnd.Intn(128))
+		}
+		if d := Diff(c, c.Reference(), 1000); d != nil {
+			diverged++
+			if !known(d) {
+				t.Errorf("program %v: %v", i, d)
+			}
+		}
+	}
+	t.Logf("%v of 1000 programs diverged", diverged)
+}
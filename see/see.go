@@ -0,0 +1,572 @@
+// Package see decompiles J1 machine code back to Forth source
+package see
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dim13/j1"
+	"github.com/dim13/j1/eforth"
+)
+
+// prim is primitive word made of inline ALU instructions
+type prim struct {
+	name string
+	seq  []j1.ALU
+}
+
+func alus(isa j1.ISA, enc ...uint16) []j1.ALU {
+	seq := make([]j1.ALU, len(enc))
+	for i, v := range enc {
+		seq[i] = isa.Decode(v).(j1.ALU)
+	}
+	return seq
+}
+
+// prims of docs/j1eforth/j1.4th and docs/j1/toolchain/basewords.fs, first
+// one wins on equal encodings, longest sequence is matched first
+var prims = func() []prim {
+	p := []prim{
+		{"noop", alus(j1.J1, 0x6000)},
+		{"+", alus(j1.J1, 0x6203)},
+		{"xor", alus(j1.J1, 0x6503)},
+		{"and", alus(j1.J1, 0x6303)},
+		{"or", alus(j1.J1, 0x6403)},
+		{"invert", alus(j1.J1, 0x6600)},
+		{"=", alus(j1.J1, 0x6703)},
+		{"<", alus(j1.J1, 0x6803)},
+		{"u<", alus(j1.J1, 0x6f03)},
+		{"swap", alus(j1.J1, 0x6180)},
+		{"dup", alus(j1.J1, 0x6081)},
+		{"drop", alus(j1.J1, 0x6103)},
+		{"over", alus(j1.J1, 0x6181)},
+		{"nip", alus(j1.J1, 0x6003)},
+		{">r", alus(j1.J1, 0x6147)},
+		{"r>", alus(j1.J1, 0x6b8d)},
+		{"r@", alus(j1.J1, 0x6b81)},
+		{"@", alus(j1.J1, 0x6c00)},
+		{"!", alus(j1.J1, 0x6023, 0x6103)},
+		{"dsp", alus(j1.J1, 0x6e81)},
+		{"lshift", alus(j1.J1, 0x6d03)},
+		{"rshift", alus(j1.J1, 0x6903)},
+		{"1-", alus(j1.J1, 0x6a00)},
+		{"2r>", alus(j1.J1, 0x6b8d, 0x6b8d, 0x6180)},
+		{"2>r", alus(j1.J1, 0x6180, 0x6147, 0x6147)},
+		{"2r@", alus(j1.J1, 0x6b8d, 0x6b8d, 0x6181, 0x6181, 0x6147, 0x6147, 0x6180)},
+		{"unloop", alus(j1.J1, 0x600c, 0x600c)},
+		{"dup@", alus(j1.J1, 0x6c81)},
+		{"dup>r", alus(j1.J1, 0x6044)},
+		{"2dupxor", alus(j1.J1, 0x6581)},
+		{"2dup=", alus(j1.J1, 0x6781)},
+		{"!nip", alus(j1.J1, 0x6023)},
+		{"2dup!", alus(j1.J1, 0x6020)},
+		{"io@", alus(j1.J1b, 0x6d00)},
+		{"io!", alus(j1.J1b, 0x6043, 0x6103)},
+		{"depths", alus(j1.J1b, 0x6e11)},
+		{"2dupand", alus(j1.J1b, 0x6311)},
+		{"2dup<", alus(j1.J1b, 0x6811)},
+		{"2dupor", alus(j1.J1b, 0x6411)},
+		{"2duprshift", alus(j1.J1b, 0x6911)},
+		{"2dup+", alus(j1.J1b, 0x6211)},
+		{"2dupu<", alus(j1.J1b, 0x6f11)},
+		{"overand", alus(j1.J1b, 0x6300)},
+		{"over>", alus(j1.J1b, 0x6800)},
+		{"over=", alus(j1.J1b, 0x6700)},
+		{"overor", alus(j1.J1b, 0x6400)},
+		{"over+", alus(j1.J1b, 0x6200)},
+		{"overu>", alus(j1.J1b, 0x6f00)},
+		{"overxor", alus(j1.J1b, 0x6500)},
+		{"rdrop", alus(j1.J1b, 0x600c)},
+	}
+	sort.SliceStable(p, func(i, j int) bool { return len(p[i].seq) > len(p[j].seq) })
+	return p
+}()
+
+var invert = alus(j1.J1, 0x6600)[0]
+
+// strip fused return off ALU instruction
+func strip(alu j1.ALU) (j1.ALU, bool) {
+	if alu.RtoPC && alu.Rdir == -1 {
+		alu.RtoPC, alu.Rdir = false, 0
+		return alu, true
+	}
+	return alu, false
+}
+
+type kind int
+
+const (
+	kWord kind = iota // plain text
+	kIf               // conditional branch
+	kJump             // unconditional branch inside of word
+	kExit             // return
+	kNext             // end of for loop
+)
+
+// token of decompiled code, addresses in cells
+type token struct {
+	kind   kind
+	pc     uint16
+	end    uint16
+	text   string
+	target uint16
+}
+
+// Decompiler of eForth words
+type Decompiler struct {
+	mem  []uint16
+	isa  j1.ISA
+	dict *eforth.Dictionary
+}
+
+// New decompiler of memory cells
+func New(mem []uint16, isa j1.ISA) (*Decompiler, error) {
+	dict, err := eforth.NewDictionary(mem)
+	if err != nil {
+		return nil, err
+	}
+	return &Decompiler{mem: mem, isa: isa, dict: dict}, nil
+}
+
+// See decompiles word by name
+func (d *Decompiler) See(name string) (string, error) {
+	w, ok := d.dict.Lookup(name)
+	if !ok {
+		return "", fmt.Errorf("%v: word not found", name)
+	}
+	return d.Word(w), nil
+}
+
+// At decompiles code at byte address addr, e.g. for debugger
+func (d *Decompiler) At(addr uint16) string {
+	if w, ok := d.dict.At(addr); ok {
+		return d.Word(w)
+	}
+	pc := addr >> 1
+	return fmt.Sprintf("( %0.4X ) :noname %v", addr, d.body(pc, uint16(len(d.mem))))
+}
+
+// Word decompiles dictionary entry
+func (d *Decompiler) Word(w eforth.Word) string {
+	pc := w.Addr >> 1
+	if int(pc) >= len(d.mem) {
+		return fmt.Sprintf(": %v ;", w.Name)
+	}
+	end := pc + (w.Size+1)>>1
+	if int(end) > len(d.mem) || end <= pc {
+		end = uint16(len(d.mem))
+	}
+	var s string
+	switch d.name(d.mem[pc]) {
+	case "dovar":
+		s = "create " + w.Name
+		for _, v := range d.mem[pc+1 : end] {
+			s += fmt.Sprintf(" %x ,", v)
+		}
+	case "douser":
+		var v uint16
+		if pc+1 < end {
+			v = d.mem[pc+1]
+		}
+		s = fmt.Sprintf("%x user %v", v, w.Name)
+	default:
+		s = fmt.Sprintf(": %v %v", w.Name, d.body(pc, end))
+	}
+	if w.Flags != 0 {
+		s += " " + w.Flags.String()
+	}
+	return s
+}
+
+// name of word called by instruction v
+func (d *Decompiler) name(v uint16) string {
+	if c, ok := d.isa.Decode(v).(j1.Call); ok {
+		if w, ok := d.dict.At(uint16(c) << 1); ok {
+			return w.Name
+		}
+	}
+	return ""
+}
+
+// body of definition, from pc up to end cell or last unconditional exit
+func (d *Decompiler) body(pc, end uint16) string {
+	ts := collapse(d.tokens(pc, end))
+	words := structure(ts)
+	if n := len(words); n > 0 && words[n-1] == "exit" {
+		words = words[:n-1]
+	}
+	return wrap(append(words, ";"))
+}
+
+// cell names word called or primitive of single instruction v
+func (d *Decompiler) cell(v uint16) string {
+	if name := d.name(v); name != "" {
+		return name
+	}
+	if alu, ok := d.isa.Decode(v).(j1.ALU); ok {
+		for _, p := range prims {
+			if len(p.seq) == 1 && p.seq[0] == alu {
+				return p.name
+			}
+		}
+	}
+	return fmt.Sprintf("[ %x , ]", v)
+}
+
+// labels are targets of branches in range
+func (d *Decompiler) labels(pc, end uint16) map[uint16]bool {
+	l := make(map[uint16]bool)
+	for ; pc < end; pc++ {
+		switch v := d.isa.Decode(d.mem[pc]).(type) {
+		case j1.Jump:
+			l[uint16(v)] = true
+		case j1.Conditional:
+			l[uint16(v)] = true
+		}
+	}
+	return l
+}
+
+func (d *Decompiler) alu(pc uint16) (j1.ALU, bool) {
+	if int(pc) >= len(d.mem) {
+		return j1.ALU{}, false
+	}
+	alu, ok := d.isa.Decode(d.mem[pc]).(j1.ALU)
+	return alu, ok
+}
+
+// prim matches primitive word at pc, returns it with number of cells and
+// fused return
+func (d *Decompiler) prim(pc, end uint16, labels map[uint16]bool) (string, uint16, bool) {
+next:
+	for _, p := range prims {
+		n := uint16(len(p.seq))
+		if pc+n > end {
+			continue
+		}
+		var ret bool
+		for i, want := range p.seq {
+			at := pc + uint16(i)
+			if i > 0 && (ret || labels[at]) {
+				continue next
+			}
+			alu, _ := d.alu(at)
+			if alu, ret = strip(alu); alu != want {
+				continue next
+			}
+		}
+		return p.name, n, ret
+	}
+	return "", 1, false
+}
+
+// str reads counted string following cell pc
+func (d *Decompiler) str(pc uint16) (string, uint16) {
+	addr := (pc + 1) << 1
+	if int(addr>>1) >= len(d.mem) {
+		return "", 0
+	}
+	n := uint16(d.byteAt(addr))
+	s := make([]byte, n)
+	for i := range s {
+		s[i] = d.byteAt(addr + 1 + uint16(i))
+	}
+	return string(s), (n + 2) >> 1
+}
+
+func (d *Decompiler) byteAt(addr uint16) uint8 {
+	if int(addr>>1) >= len(d.mem) {
+		return 0
+	}
+	return uint8(d.mem[addr>>1] >> (8 * (addr & 1)))
+}
+
+// inline forms of runtime words compiled by immediate words
+var inline = map[string]string{
+	"(i)":       "i",
+	"(leave)":   "leave",
+	"(unloop)":  "unloop",
+	"(do)":      "do",
+	"(?do)":     "?do",
+	"(loop)":    "loop",
+	"(+loop)":   "+loop",
+	"(to)":      "to",
+	"(+to)":     "+to",
+	`."|`:       `."`,
+	`$"|`:       `$"`,
+	`<?abort">`: `abort"`,
+}
+
+// tokens of code from pc up to end. It stops after unconditional return
+// if no branch goes beyond.
+func (d *Decompiler) tokens(pc, end uint16) []token {
+	labels := d.labels(pc, end)
+	start := pc
+	var ts []token
+	var reach uint16
+	emit := func(k kind, n uint16, text string, target uint16) {
+		ts = append(ts, token{kind: k, pc: pc, end: pc + n, text: text, target: target})
+	}
+	for pc < end {
+		var n uint16 = 1
+		done := false
+		switch v := d.isa.Decode(d.mem[pc]).(type) {
+		case j1.Literal:
+			x, ret := uint16(v), false
+			if pc+1 < end && !labels[pc+1] {
+				if alu, ok := d.alu(pc + 1); ok {
+					if alu, r := strip(alu); alu == invert {
+						x, n, ret = ^x, 2, r
+					}
+				} else if d.name(d.mem[pc+1]) == "invert" {
+					x, n = ^x, 2
+				}
+			}
+			emit(kWord, n, fmt.Sprintf("%x", x), 0)
+			if ret {
+				emit(kExit, n, "", 0)
+				done = reach <= pc
+			}
+		case j1.Conditional:
+			t := uint16(v)
+			emit(kIf, 1, "", t)
+			if t > reach {
+				reach = t
+			}
+		case j1.Jump:
+			t := uint16(v)
+			if t >= start && t <= end {
+				emit(kJump, 1, "", t)
+				if t > reach {
+					reach = t
+				}
+				done = t <= pc && reach <= pc
+				break
+			}
+			// tail call
+			if w, ok := d.dict.At(t << 1); ok {
+				emit(kWord, 1, w.Name, 0)
+			} else {
+				emit(kWord, 1, fmt.Sprintf("[ %x , ]", d.mem[pc]), 0)
+			}
+			emit(kExit, 1, "", 0)
+			done = reach <= pc
+		case j1.Call:
+			name := d.name(d.mem[pc])
+			if name == "" {
+				emit(kWord, 1, fmt.Sprintf("[ %x , ]", d.mem[pc]), 0)
+				break
+			}
+			text, ok := inline[name]
+			if !ok {
+				text = name
+			}
+			switch name {
+			case "compile":
+				n = 2
+				if pc+1 < end {
+					text += " " + d.cell(d.mem[pc+1])
+				}
+			case "(next)":
+				n = 2
+				if pc+1 < end {
+					emit(kNext, n, "next", d.mem[pc+1]>>1)
+					pc += n
+					continue
+				}
+			case "(do)", "(?do)":
+				n = 2
+			case "(loop)", "(+loop)":
+				n = 2
+				if pc+2 < end && d.name(d.mem[pc+2]) == "(unloop)" {
+					n = 3
+				}
+			case "(to)", "(+to)":
+				n = 2
+				if pc+1 >= end {
+					break
+				}
+				if w, ok := d.dict.At(d.mem[pc+1] - 2); ok {
+					text += " " + w.Name
+				}
+			case `."|`, `$"|`, `<?abort">`:
+				s, m := d.str(pc)
+				text += " " + s + `"`
+				n += m
+			}
+			emit(kWord, n, text, 0)
+		case j1.ALU:
+			name, m, ret := d.prim(pc, end, labels)
+			n = m
+			switch {
+			case name == "":
+				emit(kWord, n, fmt.Sprintf("[ %x , ]", d.mem[pc]), 0)
+			case name == "noop" && ret:
+			default:
+				emit(kWord, n, name, 0)
+			}
+			if ret {
+				emit(kExit, n, "", 0)
+				done = reach <= pc
+			}
+		}
+		pc += n
+		if done {
+			break
+		}
+	}
+	return ts
+}
+
+// metaNext is for loop end of metacompiled code
+//
+//	r@ if r> 1- >r again then r> drop
+var metaNext = []string{"r@", "", "r>", "1-", ">r", "", "r>", "drop"}
+
+// collapse metacompiled loop ends into next tokens
+func collapse(ts []token) []token {
+	var out []token
+	for i := 0; i < len(ts); i++ {
+		if i+len(metaNext) <= len(ts) && isMetaNext(ts[i:i+len(metaNext)]) {
+			last := ts[i+len(metaNext)-1]
+			out = append(out, token{kind: kNext, pc: ts[i].pc, end: last.end, text: "next", target: ts[i+5].target})
+			i += len(metaNext) - 1
+			continue
+		}
+		out = append(out, ts[i])
+	}
+	return out
+}
+
+func isMetaNext(ts []token) bool {
+	for i, s := range metaNext {
+		if s != "" && (ts[i].kind != kWord || ts[i].text != s) {
+			return false
+		}
+	}
+	cond, jump := ts[1], ts[5]
+	return cond.kind == kIf && cond.target == ts[6].pc &&
+		jump.kind == kJump && jump.target < jump.pc
+}
+
+// structure recovers control flow words from branches
+func structure(ts []token) []string {
+	text := make([]string, len(ts))
+	index := make(map[uint16]int)
+	for i, t := range ts {
+		index[t.pc] = i
+	}
+	// for ... [aft ... then] next
+	aft := make(map[int]bool)
+	for i, t := range ts {
+		if t.kind != kNext {
+			continue
+		}
+		j, ok := index[t.target]
+		switch {
+		case !ok:
+		case j > 0 && ts[j-1].text == ">r":
+			text[j-1] = "for"
+		case j > 0 && ts[j-1].kind == kJump:
+			// aft drops begin of for and leaves its own
+			for k := j - 2; k >= 0; k-- {
+				if ts[k].text == ">r" && text[k] == "" {
+					text[k] = "for"
+					aft[j-1] = true
+					break
+				}
+			}
+		}
+		text[i] = "next"
+	}
+	thens := make(map[uint16]int)
+	begins := make(map[uint16]bool)
+	var open []int // forward conditionals
+	pending := func(at uint16, inside uint16) int {
+		for k := len(open) - 1; k >= 0; k-- {
+			if j := open[k]; ts[j].target == at && ts[j].pc >= inside {
+				open = append(open[:k], open[k+1:]...)
+				return j
+			}
+		}
+		return -1
+	}
+	for i, t := range ts {
+		switch t.kind {
+		case kIf:
+			if t.target > t.pc {
+				text[i] = "if"
+				open = append(open, i)
+				thens[t.target]++
+			} else {
+				text[i] = "until"
+				begins[t.target] = true
+			}
+		case kJump:
+			switch {
+			case aft[i]:
+				text[i] = "aft"
+				thens[t.target]++
+			case t.target > t.pc:
+				if j := pending(t.end, 0); j >= 0 {
+					text[i] = "else"
+					thens[t.end]--
+				} else {
+					text[i] = "ahead"
+				}
+				thens[t.target]++
+			default:
+				begins[t.target] = true
+				if j := pending(t.end, t.target); j >= 0 {
+					text[j] = "while"
+					text[i] = "repeat"
+					thens[t.end]--
+				} else {
+					text[i] = "again"
+				}
+			}
+		case kExit:
+			text[i] = "exit"
+		case kWord:
+			if text[i] == "" {
+				text[i] = t.text
+			}
+		}
+	}
+	var words []string
+	mark := func(pc uint16) {
+		for n := 0; n < thens[pc]; n++ {
+			words = append(words, "then")
+		}
+		if begins[pc] {
+			words = append(words, "begin")
+		}
+	}
+	for i, t := range ts {
+		if i == 0 || ts[i-1].pc != t.pc {
+			mark(t.pc)
+		}
+		words = append(words, text[i])
+	}
+	if n := len(ts); n > 0 {
+		mark(ts[n-1].end)
+	}
+	return words
+}
+
+// wrap words into lines
+func wrap(words []string) string {
+	var b strings.Builder
+	n := 0
+	for _, w := range words {
+		if n > 0 && n+len(w) > 64 {
+			b.WriteString("\n  ")
+			n = 0
+		}
+		b.WriteString(" ")
+		b.WriteString(w)
+		n += len(w) + 1
+	}
+	return strings.TrimPrefix(b.String(), " ")
+}
@@ -0,0 +1,112 @@
+package see
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dim13/j1"
+	"github.com/dim13/j1/console"
+	"github.com/dim13/j1/eforth"
+)
+
+// boot eForth with source fed to interpreter
+func boot(t *testing.T, src string) []uint16 {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx, con := console.NewBatch(ctx, strings.NewReader(src), io.Discard)
+	vm := j1.New(con)
+	if _, err := vm.Write(eforth.Image); err != nil {
+		t.Fatal(err)
+	}
+	vm.Run(ctx)
+	return vm.Memory()
+}
+
+func TestSee(t *testing.T) {
+	mem := boot(t, `: t1 10 for r@ . next ;
+: t2 5 0 do i . loop ;
+: t3 if ." yes" else -1 then ;
+: t4 begin dup while 1- repeat drop ;
+: t5 begin 1- dup 0= until ; immediate
+: t6 [ 0 ] aft 1 then [ drop ] 2 ;
+variable v1
+`)
+	d, err := New(mem, j1.J1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name string
+		want string
+	}{
+		// metacompiled, see docs/j1eforth/j1.4th
+		{name: "?dup", want: ": ?dup dup if dup exit then ;"},
+		{name: "max", want: ": max 2dup > if drop exit then nip ;"},
+		{name: "tx!", want: ": tx! begin 7001 @ 2 and 0= until 7000 ! ;"},
+		{name: "fill", want: ": fill swap for swap aft 2dup c! 1+ then next 2drop ;"},
+		{name: "#s", want: ": #s begin # dup while repeat ;"},
+		{name: ".ok", want: `: .ok e42 3e8a @ = if ."  ok" then cr ;`},
+		{name: "literal", want: ": literal dup 8000 and if ffff xor literal compile invert else 8000 or ,\n   exit then ; immediate"},
+		{name: "constant", want: ": constant create , (does>) @ ;"},
+		// compiled by eForth
+		{name: "t1", want: ": t1 10 for r@ . next ;"},
+		{name: "t2", want: ": t2 5 0 do i . loop ;"},
+		{name: "t3", want: `: t3 if ." yes" else ffff then ;`},
+		{name: "t4", want: ": t4 begin dup while 1- repeat drop ;"},
+		{name: "t5", want: ": t5 begin 1- dup 0= until ; immediate"},
+		{name: "t6", want: ": t6 ahead 1 then 2 ;"}, // forward jump outside for
+		{name: "v1", want: "create v1 0 ,"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := d.See(tc.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+	if _, err := d.See("nosuch"); err == nil {
+		t.Error("want error")
+	}
+	ca, _ := eforth.Lookup(mem, "t4")
+	if got, want := d.At(ca+2), "( "; !strings.HasPrefix(got, want) {
+		t.Errorf("got %q, want prefix %q", got, want)
+	}
+}
+
+func TestSeeTruncated(t *testing.T) {
+	mem := boot(t, `variable v1
+: t1 10 for r@ . next ;
+: t2 5 0 do i . loop ;
+: t3 1 to v1 ;
+`)
+	full, err := New(mem, j1.J1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ name, call string }{
+		{"t1", "(next)"},
+		{"t2", "(loop)"},
+		{"t3", "(to)"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w, _ := full.dict.Lookup(tc.name)
+			pc := w.Addr >> 1
+			for full.name(mem[pc]) != tc.call {
+				pc++
+			}
+			// image ends right after call, its inline argument is missing
+			d := &Decompiler{mem: mem[:pc+1], isa: j1.J1, dict: full.dict}
+			if got := d.Word(w); !strings.HasPrefix(got, ": "+tc.name) {
+				t.Errorf("got %q", got)
+			}
+		})
+	}
+}
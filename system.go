@@ -0,0 +1,137 @@
+package j1
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+)
+
+// CoreID is i/o address reading index of core in System
+const CoreID = 0x7060
+
+// Scheduler picks core to run next turn, of n
+type Scheduler interface {
+	Next(n int) int
+}
+
+type roundRobin struct{ last int }
+
+func (r *roundRobin) Next(n int) int {
+	r.last = (r.last + 1) % n
+	return r.last
+}
+
+// RoundRobin scheduler runs cores in turn, first one first
+func RoundRobin() Scheduler {
+	return &roundRobin{last: -1}
+}
+
+type random struct{ rnd *rand.Rand }
+
+func (r random) Next(n int) int {
+	return r.rnd.Intn(n)
+}
+
+// Random scheduler picks cores at random, same seed gives same order
+func Random(seed int64) Scheduler {
+	return random{rnd: rand.New(rand.NewSource(seed))}
+}
+
+// System of cores with own registers and stacks, sharing RAM and i/o
+// devices. Each core keeps a copy of RAM, 16 KiB, and stores are mirrored
+// to the others at once, so all of them see same memory between
+// instructions. A store costs a copy and a cache invalidation per other
+// core. Protection and watchpoints set on one core apply to all of them.
+type System struct {
+	Cores    []*Core
+	Schedule Scheduler // RoundRobin by default
+	Quantum  int       // instructions per turn, one by default
+}
+
+// NewSystem of n cores on shared console
+func NewSystem(con Console, n int) *System {
+	s := &System{Schedule: RoundRobin(), Quantum: 1}
+	for i := 0; i < n; i++ {
+		c := New(con)
+		c.Attach(CoreID, 2, rom{uint16(i)})
+		s.Cores = append(s.Cores, c)
+	}
+	for _, c := range s.Cores {
+		for _, p := range s.Cores {
+			if p != c {
+				c.peers = append(c.peers, p)
+			}
+		}
+	}
+	return s
+}
+
+// group of cores sharing memory with c, c first
+func (c *Core) group() []*Core {
+	return append([]*Core{c}, c.peers...)
+}
+
+// Write memory shared by cores
+func (s *System) Write(data []byte) (int, error) {
+	for _, c := range s.Cores {
+		if n, err := c.Write(data); err != nil {
+			return n, err
+		}
+	}
+	return len(data), nil
+}
+
+// Attach device shared by cores, see Core.Attach
+func (s *System) Attach(base, size uint16, dev Device) {
+	for _, c := range s.Cores {
+		c.Attach(base, size, dev)
+	}
+}
+
+// Step runs one turn: Quantum instructions of core picked by Schedule, less
+// if it accesses i/o. It returns fault or watchpoint hit of the core.
+func (s *System) Step() error {
+	_, err := s.turn()
+	return err
+}
+
+func (s *System) turn() (*Core, error) {
+	i := s.Schedule.Next(len(s.Cores))
+	c := s.Cores[i]
+	c.hit = nil
+	if n := s.Quantum; c.fault == nil {
+		if n < 1 {
+			n = 1
+		}
+		c.run(n)
+	}
+	switch {
+	case c.fault != nil:
+		return c, fmt.Errorf("core %d: %w", i, c.fault)
+	case c.hit != nil:
+		return c, fmt.Errorf("core %d: %w", i, c.hit)
+	}
+	return c, nil
+}
+
+// Run cores until ctx is done, one of them faults or a watchpoint triggers,
+// see Core.Run
+func (s *System) Run(ctx context.Context) error {
+	done := ctx.Done()
+	for {
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+		for n := 0; n < checkEvery; n++ {
+			c, err := s.turn()
+			if err != nil {
+				return err
+			}
+			if c.io {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package j1
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func load(s *System, prog []Instruction) {
+	for i, ins := range prog {
+		for _, c := range s.Cores {
+			c.memory[i] = Encode(ins)
+		}
+	}
+}
+
+var (
+	store = ALU{Opcode: OpN, NtoAtT: true, Ddir: -1}
+	fetch = ALU{Opcode: OpAtT}
+	drop  = ALU{Opcode: OpN, Ddir: -1}
+)
+
+func TestSystem(t *testing.T) {
+	prog := []Instruction{
+		Literal(CoreID), fetch, Conditional(10),
+		// core 1 waits for core 0
+		Literal(0x100), fetch, Conditional(3),
+		Literal(7), Literal(0x102), store,
+		Jump(9),
+		// core 0
+		Literal(42), Literal(0x100), store,
+		Jump(13),
+	}
+	schedulers := map[string]func() Scheduler{
+		"round robin": RoundRobin,
+		"random":      func() Scheduler { return Random(1) },
+	}
+	for name, sched := range schedulers {
+		for _, q := range []int{1, 3, 100} {
+			s := NewSystem(&mocConsole{}, 2)
+			s.Schedule, s.Quantum = sched(), q
+			load(s, prog)
+			for k := 0; k < 1000 && (s.Cores[0].pc != 13 || s.Cores[1].pc != 9); k++ {
+				if err := s.Step(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c0, c1 := s.Cores[0], s.Cores[1]
+			if c0.pc != 13 || c1.pc != 9 {
+				t.Fatalf("%v/%v: pc %0.4X %0.4X", name, q, c0.pc<<1, c1.pc<<1)
+			}
+			if c0.memory != c1.memory || c0.memory[0x80] != 42 || c0.memory[0x81] != 7 {
+				t.Errorf("%v/%v: memory %v %v", name, q, c0.memory[0x80:0x82], c1.memory[0x80:0x82])
+			}
+		}
+	}
+}
+
+// racing cores increment shared cell
+func racing(n int) *System {
+	s := NewSystem(&mocConsole{}, n)
+	load(s, []Instruction{
+		Literal(0x100), fetch, Literal(1), ALU{Opcode: OpTplusN, Ddir: -1},
+		Literal(0x100), store, drop,
+		Jump(0),
+	})
+	return s
+}
+
+func race(seed int64) (uint16, uint64) {
+	s := racing(3)
+	s.Schedule = Random(seed)
+	for k := 0; k < 3000; k++ {
+		s.Step()
+	}
+	return s.Cores[0].memory[0x80], s.Cores[1].cycles
+}
+
+func TestSystemDeterministic(t *testing.T) {
+	v, n := race(1)
+	for i := 0; i < 3; i++ {
+		if w, m := race(1); w != v || m != n {
+			t.Fatalf("got %v after %v cycles, want %v after %v", w, m, v, n)
+		}
+	}
+	if w, m := race(2); w == v && m == n {
+		t.Errorf("seeds 1 and 2 run the same")
+	}
+	if v >= 3000/8 {
+		t.Errorf("no update lost: %v", v)
+	}
+}
+
+func TestSystemRun(t *testing.T) {
+	s := racing(2)
+	if err := s.Cores[1].Watch(Watchpoint{Addr: 0x100, On: WatchWrite, If: "V == 5"}); err != nil {
+		t.Fatal(err)
+	}
+	err := s.Run(context.Background())
+	var hit *WatchHit
+	// set on core 1, core 0 stores 5 first
+	if !errors.As(err, &hit) || !strings.HasPrefix(err.Error(), "core 0:") || hit.New != 5 {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestSystemProtect(t *testing.T) {
+	s := NewSystem(&mocConsole{}, 2)
+	load(s, []Instruction{
+		Literal(CoreID), fetch, Conditional(6),
+		Literal(7), Literal(0x102), store, // core 1 only
+		Jump(6),
+	})
+	// set on core 0, faults store of core 1
+	if err := s.Cores[0].Protect(0x100, 4, ReadOnly); err != nil {
+		t.Fatal(err)
+	}
+	err := s.Run(context.Background())
+	if !errors.Is(err, ErrWriteProtected) || !strings.HasPrefix(err.Error(), "core 1:") {
+		t.Fatalf("got %v", err)
+	}
+	if v := s.Cores[0].memory[0x81]; v != 0 {
+		t.Errorf("stored %v", v)
+	}
+}
+
+func TestSystemWatch(t *testing.T) {
+	s := NewSystem(&mocConsole{}, 2)
+	load(s, []Instruction{
+		Literal(CoreID), fetch, Conditional(6),
+		Literal(7), Literal(0x102), store, // core 1 only
+		Jump(6),
+	})
+	// set on core 0, hit by store of core 1
+	if err := s.Cores[0].Watch(Watchpoint{Addr: 0x102, On: WatchWrite, If: "V == 7"}); err != nil {
+		t.Fatal(err)
+	}
+	err := s.Run(context.Background())
+	var hit *WatchHit
+	if !errors.As(err, &hit) || !strings.HasPrefix(err.Error(), "core 1:") || hit.PC != 0x0a {
+		t.Fatalf("got %v", err)
+	}
+	s.Cores[1].Unwatch(0x102)
+	for _, c := range s.Cores {
+		if len(c.watches) != 0 {
+			t.Errorf("watchpoints left: %v", c.Watchpoints())
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Package telnet serves J1 console over telnet
+package telnet
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+
+	"github.com/dim13/j1"
+)
+
+// Telnet commands and options, see RFC 854, 857, 858
+const (
+	se   = 240 // end of subnegotiation
+	sb   = 250 // begin of subnegotiation
+	will = 251
+	wont = 252
+	do   = 253
+	dont = 254
+	iac  = 255 // interpret as command
+
+	optEcho = 1 // echo
+	optSGA  = 3 // suppress go ahead
+)
+
+const (
+	nul = 0x00
+	bs  = 0x08
+	lf  = 0x0a
+	cr  = 0x0d
+	del = 0x7f
+)
+
+// Console over telnet connection
+type Console struct {
+	conn   net.Conn
+	input  chan uint16
+	cancel func()
+	mu     sync.Mutex // guards writes to conn
+}
+
+// New console on telnet connection
+func New(ctx context.Context, conn net.Conn) (context.Context, *Console) {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &Console{
+		conn:   conn,
+		input:  make(chan uint16, 1),
+		cancel: cancel,
+	}
+	// remote echo and character at a time mode
+	c.send(iac, will, optEcho, iac, will, optSGA, iac, do, optSGA)
+	go func() {
+		defer close(c.input)
+		defer cancel()
+		r := bufio.NewReader(conn)
+		for {
+			v, err := c.next(r)
+			if err != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case c.input <- v:
+			}
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	return ctx, c
+}
+
+// next data byte, negotiations are handled on the fly
+func (c *Console) next(r *bufio.Reader) (uint16, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case iac:
+			data, err := c.command(r)
+			if err != nil {
+				return 0, err
+			}
+			if data {
+				return iac, nil
+			}
+		case cr:
+			// CR LF and CR NUL both end a line, eForth expects LF
+			if n, err := r.Peek(1); err == nil && (n[0] == lf || n[0] == nul) {
+				r.ReadByte()
+			}
+			return lf, nil
+		case del:
+			return bs, nil
+		default:
+			return uint16(b), nil
+		}
+	}
+}
+
+// command handles sequence after IAC, reports if it was escaped data byte
+func (c *Console) command(r *bufio.Reader) (bool, error) {
+	cmd, err := r.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	switch cmd {
+	case iac:
+		return true, nil
+	case will, wont, do, dont:
+		opt, err := r.ReadByte()
+		if err != nil {
+			return false, err
+		}
+		c.negotiate(cmd, opt)
+	case sb:
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return false, err
+			}
+			if b != iac {
+				continue
+			}
+			if b, err = r.ReadByte(); err != nil {
+				return false, err
+			}
+			if b == se {
+				break
+			}
+		}
+	}
+	return false, nil
+}
+
+// negotiate refuses everything but echo and suppress go ahead
+func (c *Console) negotiate(cmd, opt byte) {
+	switch cmd {
+	case do:
+		if opt != optEcho && opt != optSGA {
+			c.send(iac, wont, opt)
+		}
+	case will:
+		if opt != optSGA {
+			c.send(iac, dont, opt)
+		}
+	}
+}
+
+func (c *Console) send(b ...byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.conn.Write(b); err != nil {
+		c.cancel()
+	}
+}
+
+// Read from console
+func (c *Console) Read() uint16 {
+	return <-c.input
+}
+
+// Write to console
+func (c *Console) Write(v uint16) {
+	if b := byte(v); b == iac {
+		c.send(iac, iac)
+	} else {
+		c.send(b)
+	}
+}
+
+// Len of input buffer
+func (c *Console) Len() uint16 {
+	if len(c.input) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Stop console and close connection
+func (c *Console) Stop() {
+	c.cancel()
+}
+
+// Serve accepts telnet connections on l and runs image on a new core for each
+// client. It returns when ctx is done or l fails.
+func Serve(ctx context.Context, l net.Listener, image []byte) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session(ctx, conn, image)
+		}()
+	}
+}
+
+func session(ctx context.Context, conn net.Conn, image []byte) {
+	ctx, con := New(ctx, conn)
+	defer con.Stop()
+	vm := j1.New(con)
+	if _, err := vm.Write(image); err != nil {
+		return
+	}
+	vm.Run(ctx)
+}
@@ -0,0 +1,96 @@
+package telnet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dim13/j1/eforth"
+)
+
+// readUntil reads data bytes, skipping negotiations, until s is seen
+func readUntil(t *testing.T, r *bufio.Reader, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	for !strings.Contains(buf.String(), s) {
+		b, err := r.ReadByte()
+		if err != nil {
+			t.Fatalf("waiting for %q: %v, got %q", s, err, buf.String())
+		}
+		if b == iac {
+			cmd, _ := r.ReadByte()
+			if cmd >= will && cmd <= dont {
+				r.ReadByte()
+			}
+			continue
+		}
+		buf.WriteByte(b)
+	}
+	return buf.String()
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Serve(ctx, l, eforth.Image) }()
+
+	for _, in := range []string{"1 2 + .\r\n", "3 4 * .\r\x00"} {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		r := bufio.NewReader(conn)
+		readUntil(t, r, "eforth j1 v1.04")
+		io.WriteString(conn, in)
+		readUntil(t, r, " ok")
+		io.WriteString(conn, "bye\r\n")
+		if _, err := io.ReadAll(r); err != nil {
+			t.Error(err)
+		}
+		conn.Close()
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(time.Second))
+	out := make(chan []byte)
+	go func() {
+		buf := make([]byte, 12)
+		io.ReadFull(client, buf)
+		out <- buf
+	}()
+	ctx, con := New(context.Background(), server)
+	go io.WriteString(client, "\xff\xfd\x18a\xff\xffb\xff\xfa\x18\x01\xff\xf0c")
+
+	want := []byte{
+		iac, will, optEcho, iac, will, optSGA, iac, do, optSGA, // greeting
+		iac, wont, 0x18, // refuse terminal type
+	}
+	if got := <-out; !bytes.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, want := range []uint16{'a', iac, 'b', 'c'} {
+		if v := con.Read(); v != want {
+			t.Errorf("got %x, want %x", v, want)
+		}
+	}
+	con.Stop()
+	<-ctx.Done()
+}
@@ -0,0 +1,180 @@
+package j1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Watch is memory access a watchpoint triggers on
+type Watch uint8
+
+// Accesses, combined
+const (
+	WatchRead   Watch = 1 << iota // [T]
+	WatchWrite                    // N→[T]
+	WatchChange                   // N→[T] of value other than cell has
+)
+
+var watchNames = []struct {
+	w    Watch
+	name string
+}{
+	{WatchRead, "read"},
+	{WatchWrite, "write"},
+	{WatchChange, "change"},
+}
+
+func (w Watch) String() string {
+	var s []string
+	for _, v := range watchNames {
+		if w&v.w != 0 {
+			s = append(s, v.name)
+		}
+	}
+	return strings.Join(s, ",")
+}
+
+// Watchpoint on RAM cell
+type Watchpoint struct {
+	Addr uint16 // byte address
+	On   Watch
+	If   string // condition over T, N, R, PC, V and OLD, empty for always
+}
+
+// ParseWatchpoint of form "read,write 0x1a06 if V > 0x2000"
+func ParseWatchpoint(s string) (Watchpoint, error) {
+	var w Watchpoint
+	s, w.If, _ = strings.Cut(s, " if ")
+	f := strings.Fields(s)
+	if len(f) != 2 {
+		return w, fmt.Errorf("%v: want access and address", s)
+	}
+	for _, v := range strings.Split(f[0], ",") {
+		k := len(watchNames)
+		for i, n := range watchNames {
+			if n.name == v {
+				k = i
+			}
+		}
+		if k == len(watchNames) {
+			return w, fmt.Errorf("%v: unknown access %q", s, v)
+		}
+		w.On |= watchNames[k].w
+	}
+	addr, err := strconv.ParseUint(f[1], 0, 16)
+	if err != nil {
+		return w, err
+	}
+	w.Addr = uint16(addr)
+	return w, nil
+}
+
+func (w Watchpoint) String() string {
+	s := fmt.Sprintf("%v %#04x", w.On, w.Addr)
+	if w.If != "" {
+		s += " if " + w.If
+	}
+	return s
+}
+
+// WatchHit stops Run after instruction at PC accessed watched cell
+type WatchHit struct {
+	Watchpoint
+	PC       uint16 // byte address of instruction
+	Access   Watch  // what it did, read or write
+	Old, New uint16 // cell before and after
+}
+
+func (h *WatchHit) Error() string {
+	return fmt.Sprintf("%0.4X: %v %0.4X: %0.4X → %0.4X (%v)", h.PC, h.Access, h.Addr&^1, h.Old, h.New, h.Watchpoint)
+}
+
+type watchpoint struct {
+	Watchpoint
+	cond expr
+}
+
+// Watch memory. Run returns WatchHit once watched cell is accessed, and
+// resumes when called again. Memory accesses are slower while watchpoints
+// are set. In a System, watchpoints are set on all cores, so accesses of
+// any of them trigger them.
+func (c *Core) Watch(w Watchpoint) error {
+	if w.Addr&ioMask != 0 {
+		return fmt.Errorf("%0.4X: not in RAM", w.Addr)
+	}
+	cond, err := compile(w.If)
+	if err != nil {
+		return err
+	}
+	for _, p := range c.group() {
+		p.watches = append(p.watches, watchpoint{Watchpoint: w, cond: cond})
+		p.flush()
+	}
+	return nil
+}
+
+// Unwatch drops watchpoints on cell at byte address, on all cores of a
+// System
+func (c *Core) Unwatch(addr uint16) {
+	for _, p := range c.group() {
+		var keep []watchpoint
+		for _, w := range p.watches {
+			if w.Addr>>1 != addr>>1 {
+				keep = append(keep, w)
+			}
+		}
+		p.watches = keep
+		p.flush()
+	}
+}
+
+// Watchpoints set
+func (c *Core) Watchpoints() []Watchpoint {
+	var ws []Watchpoint
+	for _, w := range c.watches {
+		ws = append(ws, w.Watchpoint)
+	}
+	return ws
+}
+
+// Hit is watchpoint triggered by last Execute, or during last Run
+func (c *Core) Hit() *WatchHit {
+	return c.hit
+}
+
+// guarded memory accesses go through alu
+func (c *Core) guarded() bool {
+	return c.prot != nil || c.watches != nil
+}
+
+// watch checks memory at T instruction v reads or writes
+func (c *Core) watch(v *ALU) {
+	T := c.st0
+	if c.watches == nil || T&ioMask != 0 || !v.NtoAtT && v.Opcode != OpAtT {
+		return
+	}
+	old := c.memory[T>>1]
+	e := env{T: T, N: c.d.peek(), R: c.r.peek(), PC: (c.pc - 1) & pcMask << 1, Old: old}
+	for _, w := range c.watches {
+		if w.Addr>>1 != T>>1 {
+			continue
+		}
+		var access Watch
+		switch {
+		case v.NtoAtT && w.On&WatchWrite != 0:
+			access, e.V = WatchWrite, e.N
+		case v.NtoAtT && w.On&WatchChange != 0 && e.N != old:
+			access, e.V = WatchChange, e.N
+		case v.Opcode == OpAtT && w.On&WatchRead != 0:
+			access, e.V = WatchRead, old
+		default:
+			continue
+		}
+		if w.cond(&e) != 0 {
+			c.hit = &WatchHit{Watchpoint: w.Watchpoint, PC: e.PC, Access: access, Old: old, New: e.V}
+			c.io = true
+			return
+		}
+	}
+}
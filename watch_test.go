@@ -0,0 +1,138 @@
+package j1
+
+import "testing"
+
+func TestParseWatchpoint(t *testing.T) {
+	for _, s := range []string{
+		"write 0x1a06",
+		"read,change 0x0100 if V > 0x2000 && PC != 0x42",
+	} {
+		w, err := ParseWatchpoint(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.String() != s {
+			t.Errorf("got %q, want %q", w, s)
+		}
+	}
+	for _, s := range []string{"write", "poke 0x10", "read 0x10000"} {
+		if _, err := ParseWatchpoint(s); err == nil {
+			t.Errorf("%v: want error", s)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	e := &env{T: 0x100, N: 5, R: 0x20, PC: 0x42, V: 5, Old: 3}
+	testCases := []struct {
+		s    string
+		want bool
+	}{
+		{s: "", want: true},
+		{s: "V == 5", want: true},
+		{s: "v == 5", want: true},
+		{s: "T == 0x100 && N != 5", want: false},
+		{s: "OLD + 2 == V || PC < 0x40", want: true},
+		{s: "!(R >= 32)", want: false},
+		{s: "T & 0xff00 == 256", want: true},
+		{s: "-1 > N", want: true},
+		{s: "(PC - 2) ^ 0x40", want: false},
+	}
+	for _, tc := range testCases {
+		x, err := compile(tc.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := x(e) != 0; got != tc.want {
+			t.Errorf("%v: got %v, want %v", tc.s, got, tc.want)
+		}
+	}
+	for _, s := range []string{"V =", "V = 5", "(V", "W", "V 5", "0x10000"} {
+		if _, err := compile(s); err == nil {
+			t.Errorf("%v: want error", s)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	store := ALU{Opcode: OpN, NtoAtT: true, Ddir: -1}
+	prog := []Instruction{
+		Literal(5), Literal(0x100), store,
+		Literal(5), Literal(0x100), store,
+		Literal(0x100), ALU{Opcode: OpAtT},
+		Literal(6), Literal(0x100), store,
+		Jump(11),
+	}
+	testCases := []struct {
+		w    string
+		hits []uint16 // byte address of instruction
+	}{
+		{w: "write 0x100", hits: []uint16{0x04, 0x0a, 0x14}},
+		{w: "change 0x101", hits: []uint16{0x04, 0x14}},
+		{w: "read 0x100", hits: []uint16{0x0e}},
+		{w: "read,write 0x100 if V == 6 || PC == 0x0e", hits: []uint16{0x0e, 0x14}},
+		{w: "write 0x102"},
+	}
+	for _, tc := range testCases {
+		for _, e := range []string{"execute", "interp"} {
+			t.Run(tc.w+"/"+e, func(t *testing.T) {
+				c := New(&mocConsole{})
+				for i, ins := range prog {
+					c.memory[i] = Encode(ins)
+				}
+				w, err := ParseWatchpoint(tc.w)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := c.Watch(w); err != nil {
+					t.Fatal(err)
+				}
+				var hits []uint16
+				// bounded in case a hit is missed and program loops
+				for n := 0; c.pc != 11 && n < len(prog); n++ {
+					switch e {
+					case "execute":
+						c.Execute(c.Fetch())
+					case "interp":
+						c.hit = nil
+						c.run(len(prog))
+					}
+					if h := c.Hit(); h != nil {
+						hits = append(hits, h.PC)
+					}
+				}
+				if len(hits) != len(tc.hits) {
+					t.Fatalf("got %0.4X, want %0.4X", hits, tc.hits)
+				}
+				for i := range hits {
+					if hits[i] != tc.hits[i] {
+						t.Errorf("got %0.4X, want %0.4X", hits, tc.hits)
+					}
+				}
+				if c.memory[0x80] != 6 {
+					t.Errorf("memory %v, want 6", c.memory[0x80])
+				}
+			})
+		}
+	}
+}
+
+func TestUnwatch(t *testing.T) {
+	c := New(&mocConsole{})
+	for _, w := range []Watchpoint{{Addr: 0x100, On: WatchRead}, {Addr: 0x101, On: WatchWrite}, {Addr: 0x102, On: WatchWrite}} {
+		if err := c.Watch(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Watch(Watchpoint{Addr: 0x4000, On: WatchRead}); err == nil {
+		t.Error("want error")
+	}
+	c.Unwatch(0x100)
+	if ws := c.Watchpoints(); len(ws) != 1 || ws[0].Addr != 0x102 {
+		t.Errorf("got %v", ws)
+	}
+	c.Unwatch(0x102)
+	if c.guarded() {
+		t.Error("still guarded")
+	}
+}
@@ -0,0 +1,139 @@
+// Package web serves J1 console as a terminal in web browser
+package web
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+
+	"github.com/dim13/j1"
+)
+
+//go:embed index.html
+var index []byte
+
+// Console over WebSocket connection
+type Console struct {
+	ws     *wsConn
+	input  chan uint16
+	output chan byte
+	done   <-chan struct{}
+	cancel func()
+}
+
+const (
+	bs  = 0x08
+	lf  = 0x0a
+	cr  = 0x0d
+	del = 0x7f
+)
+
+// newConsole on WebSocket connection
+func newConsole(ctx context.Context, ws *wsConn) (context.Context, *Console) {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &Console{
+		ws:     ws,
+		input:  make(chan uint16, 1),
+		output: make(chan byte, 1024),
+		done:   ctx.Done(),
+		cancel: cancel,
+	}
+	go func() {
+		defer close(c.input)
+		defer cancel()
+		for {
+			msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			for _, b := range msg {
+				switch b {
+				case cr:
+					b = lf
+				case del:
+					b = bs
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case c.input <- uint16(b):
+				}
+			}
+		}
+	}()
+	go func() {
+		defer ws.Close()
+		buf := make([]byte, 0, cap(c.output))
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case b := <-c.output:
+				buf = append(buf[:0], b)
+			}
+			// collect whatever is pending into one message
+			for len(buf) < cap(buf) && len(c.output) > 0 {
+				buf = append(buf, <-c.output)
+			}
+			if err := ws.WriteMessage(buf); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+	return ctx, c
+}
+
+// Read from console
+func (c *Console) Read() uint16 {
+	return <-c.input
+}
+
+// Write to console
+func (c *Console) Write(v uint16) {
+	select {
+	case <-c.done:
+	case c.output <- byte(v):
+	}
+}
+
+// Len of input buffer
+func (c *Console) Len() uint16 {
+	if len(c.input) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Stop console and close connection
+func (c *Console) Stop() {
+	c.cancel()
+}
+
+// Handler serves web terminal at / and its WebSocket endpoint at /ws.
+// Every WebSocket session runs image on a new core.
+func Handler(image []byte) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(index)
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrade(w, r)
+		if err != nil {
+			return
+		}
+		ctx, con := newConsole(r.Context(), ws)
+		defer con.Stop()
+		vm := j1.New(con)
+		if _, err := vm.Write(image); err != nil {
+			return
+		}
+		vm.Run(ctx)
+	})
+	return mux
+}
@@ -0,0 +1,184 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dim13/j1/eforth"
+)
+
+// client is a minimal WebSocket client
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+	buf  bytes.Buffer
+}
+
+// handshake sends upgrade request with extra header lines
+func handshake(t *testing.T, srv *httptest.Server, extra string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	const key = "dGhlIHNhbXBsZSBub25jZQ=="
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\n"+
+		"Host: "+srv.Listener.Addr().String()+"\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: "+key+"\r\n"+
+		extra+"\r\n")
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, r, resp
+}
+
+func dial(t *testing.T, srv *httptest.Server) *client {
+	t.Helper()
+	conn, r, resp := handshake(t, srv, "Sec-WebSocket-Version: 13\r\n")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got %v, want %v", resp.Status, http.StatusSwitchingProtocols)
+	}
+	if got, want := resp.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	return &client{conn: conn, r: r}
+}
+
+func (c *client) send(t *testing.T, op byte, s string) {
+	t.Helper()
+	frame := appendFrame(nil, op, []byte(s), []byte{1, 2, 3, 4})
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *client) expect(t *testing.T, s string) {
+	t.Helper()
+	for !strings.Contains(c.buf.String(), s) {
+		_, op, payload, err := readFrame(c.r, false)
+		if err != nil {
+			t.Fatalf("waiting for %q: %v, got %q", s, err, c.buf.String())
+		}
+		if op == opBinary {
+			c.buf.Write(payload)
+		}
+	}
+	c.buf.Reset()
+}
+
+func TestSessions(t *testing.T) {
+	srv := httptest.NewServer(Handler(eforth.Image))
+	defer srv.Close()
+
+	a, b := dial(t, srv), dial(t, srv)
+	a.expect(t, "eforth j1 v1.04")
+	b.expect(t, "eforth j1 v1.04")
+
+	// sessions do not share dictionary
+	a.send(t, opText, ": sq dup * ;\n")
+	a.expect(t, " ok")
+	b.send(t, opText, "3 sq .\n")
+	b.expect(t, "sq?")
+	a.send(t, opText, "3 sq .\r")
+	a.expect(t, "9 ok")
+
+	// fragmented message and ping in between
+	first := appendFrame(nil, opText, []byte("4 "), []byte{5, 6, 7, 8})
+	first[0] &^= 0x80 // not final
+	b.conn.Write(first)
+	b.send(t, opPing, "")
+	b.send(t, opContinuation, "5 + .\n")
+	b.expect(t, "9 ok")
+
+	a.send(t, opText, "bye\n")
+	if _, err := io.ReadAll(a.r); err != nil {
+		t.Error(err)
+	}
+	a.conn.Close()
+	b.send(t, opClose, "")
+	b.conn.Close()
+}
+
+func TestHandshake(t *testing.T) {
+	srv := httptest.NewServer(Handler(eforth.Image))
+	defer srv.Close()
+	host := srv.Listener.Addr().String()
+	testCases := []struct {
+		name   string
+		extra  string
+		status int
+	}{
+		{name: "same origin", extra: "Sec-WebSocket-Version: 13\r\nOrigin: http://" + host + "\r\n", status: http.StatusSwitchingProtocols},
+		{name: "cross origin", extra: "Sec-WebSocket-Version: 13\r\nOrigin: http://evil.example\r\n", status: http.StatusForbidden},
+		{name: "no version", extra: "", status: http.StatusUpgradeRequired},
+		{name: "version 8", extra: "Sec-WebSocket-Version: 8\r\n", status: http.StatusUpgradeRequired},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, _, resp := handshake(t, srv, tc.extra)
+			defer conn.Close()
+			if resp.StatusCode != tc.status {
+				t.Errorf("got %v, want %v", resp.Status, tc.status)
+			}
+		})
+	}
+}
+
+func TestUnmasked(t *testing.T) {
+	srv := httptest.NewServer(Handler(eforth.Image))
+	defer srv.Close()
+	c := dial(t, srv)
+	defer c.conn.Close()
+	c.expect(t, "eforth j1 v1.04")
+	c.conn.Write(appendFrame(nil, opText, []byte("1 .\n"), nil))
+	for {
+		_, op, payload, err := readFrame(c.r, false)
+		if err != nil {
+			t.Fatalf("connection not closed: %v", err)
+		}
+		if op == opClose {
+			if len(payload) < 2 || binary.BigEndian.Uint16(payload) != closeProtocol {
+				t.Errorf("close payload %v", payload)
+			}
+			break
+		}
+	}
+	if _, err := io.ReadAll(c.r); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	srv := httptest.NewServer(Handler(eforth.Image))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !bytes.Contains(body, []byte("new WebSocket")) {
+		t.Errorf("got %q", body)
+	}
+	resp, err = http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v, want %v", resp.Status, http.StatusBadRequest)
+	}
+}
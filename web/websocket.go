@@ -0,0 +1,236 @@
+package web
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// WebSocket opcodes, see RFC 6455
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxPayload limits size of incoming frames, keystrokes are small
+const maxPayload = 1 << 16
+
+var (
+	errPayload  = errors.New("websocket: payload too large")
+	errUnmasked = errors.New("websocket: unmasked client frame")
+)
+
+// closeProtocol is status of close frame sent on protocol errors
+const closeProtocol = 1002
+
+// wsConn is a minimal server side WebSocket connection
+type wsConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	mu   sync.Mutex // guards writes
+}
+
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func headerContains(h http.Header, name, value string) bool {
+	for _, v := range h.Values(name) {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin tells if browser opened connection from page of this host.
+// Clients other than browsers send no Origin.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// upgrade HTTP request to WebSocket connection
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket expected", http.StatusBadRequest)
+		return nil, errors.New("websocket: bad handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "websocket version 13 expected", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: unsupported version")
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross origin websocket", http.StatusForbidden)
+		return nil, errors.New("websocket: cross origin request")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return nil, errors.New("websocket: hijacking not supported")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\n")
+	rw.WriteString("Connection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, r: rw.Reader}, nil
+}
+
+// readFrame returns final flag, opcode and unmasked payload of next frame.
+// Server requires frames to be masked, as RFC 6455 says clients do.
+func readFrame(r *bufio.Reader, requireMask bool) (bool, byte, []byte, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := hdr[0]&0x80 != 0
+	op := hdr[0] & 0xf
+	masked := hdr[1]&0x80 != 0
+	if requireMask && !masked {
+		return false, 0, nil, errUnmasked
+	}
+	size := uint64(hdr[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if size > maxPayload {
+		return false, 0, nil, errPayload
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// appendFrame appends final frame with optional mask to b
+func appendFrame(b []byte, op byte, payload []byte, mask []byte) []byte {
+	b = append(b, 0x80|op)
+	var m byte
+	if mask != nil {
+		m = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		b = append(b, m|byte(n))
+	case n <= 0xffff:
+		b = append(b, m|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, m|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	if mask == nil {
+		return append(b, payload...)
+	}
+	b = append(b, mask...)
+	for i, v := range payload {
+		b = append(b, v^mask[i%4])
+	}
+	return b
+}
+
+// ReadMessage returns payload of next data message, control frames are
+// handled on the fly
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := readFrame(c.r, true)
+		if err == errUnmasked {
+			c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, closeProtocol))
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opText, opBinary, opContinuation:
+			msg = append(msg, payload...)
+			if len(msg) > maxPayload {
+				return nil, errPayload
+			}
+			// fragmented messages are collected until final frame
+			if fin {
+				return msg, nil
+			}
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+		case opClose:
+			c.writeFrame(opClose, payload)
+			return nil, io.EOF
+		}
+	}
+}
+
+// WriteMessage sends binary message
+func (c *wsConn) WriteMessage(data []byte) error {
+	return c.writeFrame(opBinary, data)
+}
+
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.conn.Write(appendFrame(nil, op, payload, nil))
+	return err
+}
+
+// Close connection
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}